@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
+)
+
+// listMembers are the properties every list answers to.
+var listMembers = []string{"get", "length", "pop", "push", "set"}
+
+// complete offers keywords and variables for a bare word, or the fields
+// and methods of the value a dotted chain like `a.b.` refers to.
+func (r *repl) complete(line []rune, pos int) ([]string, int) {
+	start := pos
+	for start > 0 && isIdentifierRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	var names []string
+	if start > 0 && line[start-1] == '.' {
+		names = r.members(chainBefore(line, start-1))
+	} else {
+		names = append(lox.Keywords(), r.variables()...)
+	}
+
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, start
+}
+
+// variables lists the names visible from the session's current scope.
+func (r *repl) variables() []string {
+	var names []string
+	for env := r.session.Interpreter().Environment(); env != nil; env = env.Enclosing() {
+		names = append(names, env.Names()...)
+	}
+	return names
+}
+
+// members resolves a chain of names by following variables and fields only,
+// so completing never runs script code.
+func (r *repl) members(chain []string) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	found := false
+	for env := r.session.Interpreter().Environment(); env != nil && !found; env = env.Enclosing() {
+		value, found = env.Lookup(chain[0])
+	}
+	if !found {
+		return nil
+	}
+
+	for _, name := range chain[1:] {
+		switch object := value.(type) {
+		case *lox.LoxInstance:
+			value, found = object.Field(name)
+		case *lox.LoxMap:
+			value, found = object.Entries()[name]
+		default:
+			found = false
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	switch object := value.(type) {
+	case *lox.LoxInstance:
+		return append(object.FieldNames(), object.Class().MethodNames()...)
+	case *lox.LoxMap:
+		return object.Keys()
+	case *lox.LoxList:
+		return listMembers
+	}
+	return nil
+}
+
+// chainBefore returns the dotted identifiers ending just before the dot at
+// index dot, e.g. ["a", "b"] for `a.b.`.
+func chainBefore(line []rune, dot int) []string {
+	var chain []string
+	end := dot
+	for {
+		start := end
+		for start > 0 && isIdentifierRune(line[start-1]) {
+			start--
+		}
+		if start == end {
+			return nil
+		}
+		chain = append([]string{string(line[start:end])}, chain...)
+		if start == 0 || line[start-1] != '.' {
+			return chain
+		}
+		end = start - 1
+	}
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
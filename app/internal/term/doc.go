@@ -0,0 +1,4 @@
+// Package term detects terminals and switches them to raw mode for the
+// REPL's line editor. It lives in its own package so the build tags on its
+// files apply even when the command is built with `go build app/*.go`.
+package term
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package term
+
+import "errors"
+
+// IsTerminal reports whether fd refers to a terminal.
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// MakeRaw is not supported outside Linux.
+func MakeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw mode is only supported on Linux")
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/interpreter-starter-go/app/internal/term"
+)
+
+const maxHistory = 1000
+
+// errLineInterrupted is returned by readLine when Ctrl-C abandons the line.
+var errLineInterrupted = errors.New("interrupted")
+
+// completer returns the candidates for the word ending at pos in line and
+// the index where that word starts.
+type completer func(line []rune, pos int) (candidates []string, start int)
+
+// lineEditor reads lines with Emacs-style editing, history and tab
+// completion when stdin is a terminal, and falls back to plain buffered
+// reads otherwise.
+type lineEditor struct {
+	in          *bufio.Reader
+	out         io.Writer
+	fd          int
+	terminal    bool
+	complete    completer
+	history     []string
+	historyPath string
+}
+
+// newLineEditor reads lines from in, the buffered reader over file. Pass
+// the same reader to anything else that reads file so no input is lost in
+// a second buffer.
+func newLineEditor(file *os.File, in *bufio.Reader, out io.Writer, complete completer) *lineEditor {
+	e := &lineEditor{
+		in:       in,
+		out:      out,
+		fd:       int(file.Fd()),
+		terminal: term.IsTerminal(int(file.Fd())),
+		complete: complete,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		e.historyPath = filepath.Join(home, ".lox_history")
+		e.loadHistory()
+	}
+	return e
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if !e.terminal {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := term.MakeRaw(e.fd)
+	if err != nil {
+		e.terminal = false
+		return e.readLine(prompt)
+	}
+	defer restore()
+
+	line, err := e.edit(prompt)
+	if err != io.EOF {
+		fmt.Fprint(e.out, "\r\n")
+	}
+	if err == nil {
+		e.addHistory(line)
+	}
+	return line, err
+}
+
+// edit runs the key loop for one line in raw mode.
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	historyIndex := len(e.history)
+	pending := ""
+
+	refresh := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(line string) {
+		buf = []rune(line)
+		pos = len(buf)
+		refresh()
+	}
+
+	refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			return string(buf), nil
+		case 3: // Ctrl-C
+			return "", errLineInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				refresh()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				refresh()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			refresh()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			refresh()
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+				refresh()
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+				refresh()
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+			refresh()
+		case 21: // Ctrl-U
+			buf = append([]rune{}, buf[pos:]...)
+			pos = 0
+			refresh()
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+			refresh()
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+			refresh()
+		case '\t':
+			buf, pos = e.completeAt(buf, pos)
+			refresh()
+		case 16, 14: // Ctrl-P, Ctrl-N
+			historyIndex, pending = e.browse(r == 16, historyIndex, pending, string(buf), setLine)
+		case 27: // escape sequence
+			switch e.escape() {
+			case "[A", "OA":
+				historyIndex, pending = e.browse(true, historyIndex, pending, string(buf), setLine)
+			case "[B", "OB":
+				historyIndex, pending = e.browse(false, historyIndex, pending, string(buf), setLine)
+			case "[C", "OC":
+				if pos < len(buf) {
+					pos++
+					refresh()
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+					refresh()
+				}
+			case "[H", "OH", "[1~":
+				pos = 0
+				refresh()
+			case "[F", "OF", "[4~":
+				pos = len(buf)
+				refresh()
+			case "[3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					refresh()
+				}
+			}
+		default:
+			if r >= ' ' {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+				refresh()
+			}
+		}
+	}
+}
+
+// escape reads the rest of an ANSI escape sequence after ESC.
+func (e *lineEditor) escape() string {
+	first, _, err := e.in.ReadRune()
+	if err != nil || (first != '[' && first != 'O') {
+		return ""
+	}
+	sequence := []rune{first}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		sequence = append(sequence, r)
+		if r >= 0x40 && r <= 0x7e {
+			return string(sequence)
+		}
+	}
+}
+
+// browse moves through history. pending keeps the line being typed before
+// browsing started so moving past the newest entry brings it back.
+func (e *lineEditor) browse(older bool, index int, pending, current string, setLine func(string)) (int, string) {
+	if index == len(e.history) {
+		pending = current
+	}
+	if older && index > 0 {
+		index--
+	} else if !older && index < len(e.history) {
+		index++
+	} else {
+		return index, pending
+	}
+
+	if index == len(e.history) {
+		setLine(pending)
+	} else {
+		setLine(e.history[index])
+	}
+	return index, pending
+}
+
+func (e *lineEditor) completeAt(buf []rune, pos int) ([]rune, int) {
+	if e.complete == nil {
+		return buf, pos
+	}
+	candidates, start := e.complete(buf, pos)
+	if len(candidates) == 0 {
+		return buf, pos
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(candidates) > 1 && len([]rune(prefix)) <= pos-start {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		return buf, pos
+	}
+
+	completed := append([]rune{}, buf[:start]...)
+	completed = append(completed, []rune(prefix)...)
+	newPos := len(completed)
+	completed = append(completed, buf[pos:]...)
+	return completed, newPos
+}
+
+func commonPrefix(words []string) string {
+	sorted := append([]string{}, words...)
+	sort.Strings(sorted)
+	first, last := []rune(sorted[0]), []rune(sorted[len(sorted)-1])
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+	return string(first[:n])
+}
+
+func (e *lineEditor) loadHistory() {
+	data, err := os.ReadFile(e.historyPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// addHistory records line and appends it to the history file. Blank lines
+// and immediate repeats are skipped.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+
+	if e.historyPath == "" {
+		return
+	}
+	file, err := os.OpenFile(e.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
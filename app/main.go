@@ -1,87 +1,199 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+
+	"github.com/codecrafters-io/interpreter-starter-go/app/internal/term"
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
 )
 
 func main() {
+	if len(os.Args) == 2 && os.Args[1] == "repl" {
+		runRepl()
+		return
+	}
+
 	fmt.Fprintln(os.Stderr, "Logs from your program will appear here!")
 
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh tokenize <filename>")
-		os.Exit(1)
+		usage()
 	}
 
 	command := os.Args[1]
-	filename := os.Args[2]
+	switch command {
+	case "run":
+		runProgram(os.Args[2:])
+		return
+	case "ast":
+		runAst(os.Args[2:])
+		return
+	case "tokenize":
+		runTokenize(os.Args[2:])
+		return
+	case "check":
+		runCheck(os.Args[2:])
+		return
+	case "fmt":
+		runFmt(os.Args[2:])
+		return
+	case "test":
+		runTests(os.Args[2:])
+		return
+	}
 
-	bytes, err := os.ReadFile(filename)
+	source, err := readSource(os.Args[2])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
 		os.Exit(1)
 	}
 
 	switch command {
-	case "tokenize":
-		runTokenize(string(bytes))
 	case "parse":
-		runParse(string(bytes))
+		runParse(source)
 	case "evaluate":
-		runEvaluate(string(bytes))
-	case "run":
-		runProgram(string(bytes))
+		runEvaluate(source)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		os.Exit(64)
 	}
 }
 
-func runProgram(source string) error {
-	scanner := NewScanner(source)
-	tokens, scanErrors := scanner.ScanTokens()
-	if len(scanErrors) > 0 {
-		for _, err := range scanErrors {
-			fmt.Fprintln(os.Stderr, err)
-		}
-		os.Exit(65)
-		return nil
-	}
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh <parse|evaluate> <filename>")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh tokenize [--format=text|json] <filename>")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh check <filename>...")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh fmt [--check] <filename>...")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh test [--format=tap|junit] <dir|file>...")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh ast [--format=sexpr|json] <filename>")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh run [-e source | filename | -]... [-- args...]")
+	fmt.Fprintln(os.Stderr, "       ./your_program.sh repl")
+	os.Exit(1)
+}
 
-	parser := NewParser(tokens)
-	statements, err := parser.parse()
+// parseFlags parses a command's flags and returns the source named by its
+// single filename argument.
+func parseFlags(flags *flag.FlagSet, arguments []string) string {
+	flags.Parse(arguments)
+	if flags.NArg() != 1 {
+		usage()
+	}
+	source, err := readSource(flags.Arg(0))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
-		os.Exit(65)
-		return nil
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
 	}
+	return source
+}
 
-	interpreter := NewInterpreter()
-	resolver := NewResolver(interpreter)
+// readSource reads a file, or stdin when filename is "-".
+func readSource(filename string) (string, error) {
+	var bytes []byte
+	var err error
+	if filename == "-" {
+		bytes, err = io.ReadAll(os.Stdin)
+	} else {
+		bytes, err = os.ReadFile(filename)
+	}
+	return string(bytes), err
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			if parseErr, ok := r.(*ParseError); ok {
-				fmt.Fprintln(os.Stderr, parseErr.Error())
-				os.Exit(65)
+// runProgram runs each file, `-` (stdin) or `-e` source in order against
+// one set of globals. Arguments after `--` are passed to the scripts as the
+// `args` list.
+func runProgram(arguments []string) {
+	var sources []namedSource
+	scriptArgs := []string{}
+	for index := 0; index < len(arguments); index++ {
+		switch argument := arguments[index]; argument {
+		case "--":
+			scriptArgs = append(scriptArgs, arguments[index+1:]...)
+			index = len(arguments)
+		case "-e":
+			if index+1 == len(arguments) {
+				fmt.Fprintln(os.Stderr, "Error: -e requires a source argument")
+				os.Exit(64)
+			}
+			index++
+			sources = append(sources, namedSource{name: "-e", text: arguments[index]})
+		default:
+			source, err := readSource(argument)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+				os.Exit(1)
 			}
-			os.Exit(65)
+			sources = append(sources, namedSource{name: sourceName(argument), text: source})
 		}
-	}()
+	}
+	if len(sources) == 0 {
+		usage()
+	}
 
-	resolver.Resolve(statements)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	if err := interpreter.Interpret(statements); err != nil {
-		if _, ok := err.(*RuntimeError); ok {
-			os.Exit(70)
+	interpreter := newInterpreter()
+	if err := interpreter.SetGlobal("args", scriptArgs); err != nil {
+		panic(err)
+	}
+	for _, source := range sources {
+		if err := interpreter.RunFileContext(ctx, source.name, source.text); err != nil {
+			reportError(source.name, source.text, err)
+			os.Exit(exitCode(err))
 		}
-		return err
 	}
-	return nil
 }
 
+type namedSource struct {
+	name string
+	text string
+}
+
+// sourceName is the name diagnostics use for a file argument.
+func sourceName(filename string) string {
+	if filename == "-" {
+		return "<stdin>"
+	}
+	return filename
+}
+
+// reportError renders err against the source it came from, in color when
+// stderr is a terminal.
+func reportError(filename, source string, err error) {
+	renderer := lox.DiagnosticRenderer{Filename: filename, Source: source, Color: useColor(os.Stderr)}
+	renderer.Render(os.Stderr, err)
+}
+
+// useColor reports whether output to file should be colored: only on a
+// terminal, and not when NO_COLOR is set.
+func useColor(file *os.File) bool {
+	return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(file.Fd()))
+}
+
+// exitCode maps an interpreter error to the sysexits-style codes used by
+// jlox: 65 for static errors and 70 for runtime errors. A script stopped by
+// Ctrl-C exits with 130 like other interrupted commands.
+func exitCode(err error) int {
+	if errors.Is(err, lox.ErrInterrupted) {
+		return 130
+	}
+	var runtimeErr *lox.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return 70
+	}
+	return 65
+}
+
+// runParse and runEvaluate print errors in the plain format the
+// codecrafters stages check, not through the diagnostic renderer.
 func runParse(source string) error {
-	scanner := NewScanner(source)
+	scanner := lox.NewScanner(source)
 	tokens, scanErrors := scanner.ScanTokens()
 	if len(scanErrors) > 0 {
 		for _, err := range scanErrors {
@@ -90,81 +202,261 @@ func runParse(source string) error {
 		os.Exit(65)
 	}
 
-	parser := NewParser(tokens)
-	expr, err := parser.expression()
+	tree, err := printAst(tokens)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(65)
 	}
-
-	printer := AstPrinter{}
-	fmt.Println(printer.Print(expr))
+	if tree != "" {
+		fmt.Println(tree)
+	}
 	return nil
 }
 
-func runTokenize(source string) {
-	scanner := NewScanner(source)
-	tokens, errors := scanner.ScanTokens()
+// printAst renders tokens as S-expressions. A lone expression prints in the
+// bare form the parse stage has always used; anything else is parsed as a
+// program, one statement per line.
+func printAst(tokens []lox.Token) (string, error) {
+	printer := lox.AstPrinter{}
+	parser := lox.NewParser(tokens)
+	if expr, err := parser.ParseExpression(); err == nil && parser.AtEnd() {
+		return printer.Print(expr), nil
+	}
 
-	for _, token := range tokens {
-		var literalStr string
-		if token.Literal == nil {
-			literalStr = "null"
-		} else if token.Type == NUMBER {
-			switch v := token.Literal.(type) {
-			case float64:
-				if v == float64(int(v)) {
-					literalStr = fmt.Sprintf("%.1f", v)
-				} else {
-					literalStr = fmt.Sprintf("%g", v)
+	statements, err := lox.NewParser(tokens).Parse()
+	if err != nil {
+		return "", err
+	}
+	return printer.PrintProgram(statements), nil
+}
+
+// runCheck reports the static errors in each file without running any of
+// them, exiting 65 if there were any.
+func runCheck(filenames []string) {
+	failed := false
+	for _, filename := range filenames {
+		source, err := readSource(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+		for _, diagnostic := range lox.Check(source) {
+			reportError(sourceName(filename), source, diagnostic)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(65)
+	}
+}
+
+// runFmt rewrites each file in canonical form, or prints the result for
+// `-`. With --check nothing is written; files that would change are listed
+// and the exit status is 1.
+func runFmt(arguments []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list files that are not formatted instead of rewriting them")
+	flags.Parse(arguments)
+	if flags.NArg() == 0 {
+		usage()
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		source, err := readSource(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+		formatted, err := lox.Format(source)
+		if err != nil {
+			reportError(sourceName(filename), source, err)
+			status = 65
+			continue
+		}
+
+		switch {
+		case *check:
+			if formatted != source {
+				fmt.Println(filename)
+				if status == 0 {
+					status = 1
 				}
-			case int:
-				literalStr = fmt.Sprintf("%.1f", float64(v))
-			default:
-				literalStr = fmt.Sprintf("%v", token.Literal)
 			}
-		} else {
-			literalStr = fmt.Sprintf("%v", token.Literal)
+		case filename == "-":
+			fmt.Print(formatted)
+		case formatted != source:
+			if err := os.WriteFile(filename, []byte(formatted), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+				os.Exit(1)
+			}
 		}
-		fmt.Printf("%s %s %s\n", token.Type, token.Lexeme, literalStr)
 	}
+	os.Exit(status)
+}
 
-	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Fprintln(os.Stderr, err)
-		}
+// runAst prints the syntax tree of a whole program, as S-expressions or as
+// JSON that lox.UnmarshalAST can read back.
+func runAst(arguments []string) {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	format := flags.String("format", "sexpr", "output format: sexpr or json")
+	source := parseFlags(flags, arguments)
+	filename := sourceName(flags.Arg(0))
+
+	tokens, scanErrors := lox.NewScanner(source).ScanTokens()
+	if len(scanErrors) > 0 {
+		reportError(filename, source, errors.Join(scanErrors...))
 		os.Exit(65)
 	}
+	statements, err := lox.NewParser(tokens).Parse()
+	if err != nil {
+		reportError(filename, source, err)
+		os.Exit(65)
+	}
+
+	switch *format {
+	case "sexpr":
+		printer := lox.AstPrinter{}
+		fmt.Println(printer.PrintProgram(statements))
+	case "json":
+		encoded, err := lox.MarshalAST(statements)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(70)
+		}
+		fmt.Println(string(encoded))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *format)
+		os.Exit(64)
+	}
 }
 
-func runEvaluate(source string) {
-	scanner := NewScanner(source)
+func runTokenize(arguments []string) {
+	flags := flag.NewFlagSet("tokenize", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	source := parseFlags(flags, arguments)
+
+	scanner := lox.NewScanner(source)
 	tokens, scanErrors := scanner.ScanTokens()
-	if len(scanErrors) > 0 {
+
+	switch *format {
+	case "text":
+		for _, token := range tokens {
+			fmt.Println(formatToken(token))
+		}
 		for _, err := range scanErrors {
 			fmt.Fprintln(os.Stderr, err)
 		}
-		os.Exit(65)
+	case "json":
+		printTokensJSON(tokens, scanErrors)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *format)
+		os.Exit(64)
 	}
-	parser := NewParser(tokens)
-	expression, err := parser.parseExpression()
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing: %v\n", err)
+	if len(scanErrors) > 0 {
 		os.Exit(65)
 	}
-	interpreter := NewInterpreter()
-	defer func() {
-		if r := recover(); r != nil {
-			if runtimeErr, ok := r.(*RuntimeError); ok {
-				fmt.Fprintln(os.Stderr, runtimeErr.Error())
-				os.Exit(70)
+}
+
+type jsonToken struct {
+	Type    lox.TokenType `json:"type"`
+	Lexeme  string        `json:"lexeme"`
+	Literal interface{}   `json:"literal"`
+	Line    int           `json:"line"`
+	Column  int           `json:"column"`
+	Start   int           `json:"start"`
+	End     int           `json:"end"`
+}
+
+type jsonScanError struct {
+	Error  string `json:"error"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// printTokensJSON writes one JSON object per line, with scan errors placed
+// among the tokens by source offset.
+func printTokensJSON(tokens []lox.Token, scanErrors []error) {
+	encoder := json.NewEncoder(os.Stdout)
+	next := 0
+	for _, token := range tokens {
+		for ; next < len(scanErrors) && errorStart(scanErrors[next]) <= token.Start; next++ {
+			encoder.Encode(newJSONScanError(scanErrors[next]))
+		}
+		encoder.Encode(jsonToken{
+			Type:    token.Type,
+			Lexeme:  token.Lexeme,
+			Literal: token.Literal,
+			Line:    token.Line,
+			Column:  token.Column,
+			Start:   token.Start,
+			End:     token.End,
+		})
+	}
+}
+
+func errorStart(err error) int {
+	var scanErr *lox.ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr.Start()
+	}
+	return 0
+}
+
+func newJSONScanError(err error) jsonScanError {
+	var scanErr *lox.ScanError
+	if !errors.As(err, &scanErr) {
+		return jsonScanError{Error: err.Error()}
+	}
+	return jsonScanError{
+		Error:  scanErr.Message(),
+		Line:   scanErr.Line(),
+		Column: scanErr.Column(),
+		Start:  scanErr.Start(),
+		End:    scanErr.End(),
+	}
+}
+
+// formatToken renders a token as `TYPE lexeme literal`, the format expected
+// by the tokenize command.
+func formatToken(token lox.Token) string {
+	var literalStr string
+	if token.Literal == nil {
+		literalStr = "null"
+	} else if token.Type == lox.NUMBER {
+		switch v := token.Literal.(type) {
+		case float64:
+			if v == float64(int(v)) {
+				literalStr = fmt.Sprintf("%.1f", v)
+			} else {
+				literalStr = fmt.Sprintf("%g", v)
 			}
-			panic(r)
+		case int:
+			literalStr = fmt.Sprintf("%.1f", float64(v))
+		default:
+			literalStr = fmt.Sprintf("%v", token.Literal)
 		}
-	}()
-	fmt.Fprintf(os.Stderr, "Global keys: %v\n", interpreter.globals.values)
+	} else {
+		literalStr = fmt.Sprintf("%v", token.Literal)
+	}
+	return fmt.Sprintf("%s %s %s", token.Type, token.Lexeme, literalStr)
+}
+
+func runEvaluate(source string) {
+	interpreter := newInterpreter()
+	result, err := interpreter.Eval(source)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
+	}
+	fmt.Println(result)
+}
 
-	result := interpreter.Evaluate(expression)
-	fmt.Println(interpreter.stringify(result))
+// newInterpreter returns an interpreter for scripts the user runs locally,
+// which are trusted with every capability.
+func newInterpreter() *lox.Interpreter {
+	return lox.New(lox.Options{Capabilities: lox.CapAll})
 }
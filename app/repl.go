@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
+)
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+)
+
+type repl struct {
+	session *lox.Session
+	out     io.Writer
+	errOut  io.Writer
+	// color enables colored diagnostics.
+	color bool
+}
+
+func newRepl(in io.Reader, out, errOut io.Writer) *repl {
+	return &repl{
+		session: lox.NewSession(lox.Options{
+			Stdout:       out,
+			Stdin:        in,
+			Capabilities: lox.CapAll,
+		}),
+		out:    out,
+		errOut: errOut,
+	}
+}
+
+func runRepl() {
+	// The editor and readLine share one buffer so neither reads ahead of
+	// input meant for the other.
+	stdin := bufio.NewReader(os.Stdin)
+	r := newRepl(stdin, os.Stdout, os.Stderr)
+	defer r.session.Close()
+	r.color = useColor(os.Stderr)
+	editor := newLineEditor(os.Stdin, stdin, r.out, r.complete)
+
+	var chunk strings.Builder
+	for {
+		linePrompt := prompt
+		if chunk.Len() > 0 {
+			linePrompt = continuationPrompt
+		}
+		line, err := editor.readLine(linePrompt)
+		if errors.Is(err, errLineInterrupted) {
+			chunk.Reset()
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(r.out)
+			return
+		}
+
+		if chunk.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if quit := r.command(strings.TrimSpace(line)); quit {
+				return
+			}
+			continue
+		}
+
+		chunk.WriteString(line)
+		chunk.WriteString("\n")
+		if needsMoreInput(chunk.String()) {
+			continue
+		}
+		r.eval(chunk.String())
+		chunk.Reset()
+	}
+}
+
+// eval runs one complete chunk and prints the value of a trailing bare
+// expression. Ctrl-C interrupts the chunk without leaving the REPL.
+func (r *repl) eval(source string) {
+	if strings.TrimSpace(source) == "" {
+		return
+	}
+	r.run("<repl>", withSemicolon(source))
+}
+
+// run runs source as a chunk from filename, which diagnostics name.
+func (r *repl) run(filename, source string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := r.session.RunFileContext(ctx, filename, source)
+	if err != nil {
+		renderer := lox.DiagnosticRenderer{Filename: filename, Source: source, Color: r.color}
+		renderer.Render(r.errOut, err)
+		return
+	}
+	if result.IsExpression {
+		fmt.Fprintln(r.out, result.Value)
+	}
+}
+
+// needsMoreInput reports whether source stops inside a block, a
+// parenthesized expression or a string, so the REPL should keep reading.
+func needsMoreInput(source string) bool {
+	tokens, scanErrors := lox.NewScanner(source).ScanTokens()
+	for _, err := range scanErrors {
+		var scanErr *lox.ScanError
+		if errors.As(err, &scanErr) && scanErr.Message() == "Unterminated string." {
+			return true
+		}
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type {
+		case lox.LEFT_BRACE, lox.LEFT_PAREN:
+			depth++
+		case lox.RIGHT_BRACE, lox.RIGHT_PAREN:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// withSemicolon lets a bare expression like `1 + 2` be typed without its
+// terminating semicolon.
+func withSemicolon(source string) string {
+	trimmed := strings.TrimSpace(source)
+	if strings.HasSuffix(trimmed, ";") || strings.HasSuffix(trimmed, "}") {
+		return source
+	}
+	return trimmed + ";"
+}
+
+const replHelp = `Commands:
+  :tokens <src>   show the tokens the scanner produces for src
+  :ast <src>      show the syntax tree of src
+  :env            list variables from the globals down to the current scope
+  :load <file>    run a file in this session
+  :time <src>     run src and report how long it took
+  :reset          discard every definition and start over
+  :help           show this help
+  :quit           leave the REPL`
+
+// command runs a meta-command and reports whether the REPL should exit.
+func (r *repl) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":tokens":
+		r.showTokens(arg)
+	case ":ast":
+		r.showAst(arg)
+	case ":env":
+		r.showEnv()
+	case ":load":
+		r.load(arg)
+	case ":time":
+		start := time.Now()
+		r.eval(arg)
+		fmt.Fprintf(r.out, "(%s)\n", time.Since(start).Round(time.Microsecond))
+	case ":reset":
+		r.session.Reset()
+		fmt.Fprintln(r.out, "Session reset.")
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+	case ":quit", ":q":
+		return true
+	default:
+		fmt.Fprintf(r.errOut, "Unknown command %s. Type :help for a list.\n", name)
+	}
+	return false
+}
+
+func (r *repl) showTokens(source string) {
+	tokens, scanErrors := lox.NewScanner(source).ScanTokens()
+	for _, token := range tokens {
+		fmt.Fprintln(r.out, formatToken(token))
+	}
+	for _, err := range scanErrors {
+		fmt.Fprintln(r.errOut, err)
+	}
+}
+
+func (r *repl) showAst(source string) {
+	tokens, scanErrors := lox.NewScanner(source).ScanTokens()
+	if len(scanErrors) > 0 {
+		fmt.Fprintln(r.errOut, errors.Join(scanErrors...))
+		return
+	}
+	tree, err := printAst(tokens)
+	if err != nil {
+		fmt.Fprintln(r.errOut, err)
+		return
+	}
+	fmt.Fprintln(r.out, tree)
+}
+
+// showEnv prints each scope from the globals down to the innermost one.
+func (r *repl) showEnv() {
+	var scopes []*lox.Environment
+	for env := r.session.Interpreter().Environment(); env != nil; env = env.Enclosing() {
+		scopes = append([]*lox.Environment{env}, scopes...)
+	}
+
+	for depth, env := range scopes {
+		if depth == 0 {
+			fmt.Fprintln(r.out, "globals:")
+		} else {
+			fmt.Fprintf(r.out, "scope %d:\n", depth)
+		}
+		for _, name := range env.Names() {
+			value, _ := env.Lookup(name)
+			fmt.Fprintf(r.out, "  %s = %s\n", name, lox.Stringify(value))
+		}
+	}
+}
+
+func (r *repl) load(path string) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(r.errOut, "Error reading file: %v\n", err)
+		return
+	}
+	r.run(path, string(source))
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
+)
+
+// testResult is the outcome of one test function, or of loading a file
+// when name is empty.
+type testResult struct {
+	file     string
+	name     string
+	err      error
+	line     int
+	output   string
+	duration time.Duration
+}
+
+func (r testResult) passed() bool {
+	return r.err == nil
+}
+
+func (r testResult) title() string {
+	if r.name == "" {
+		return r.file
+	}
+	return r.file + ": " + r.name
+}
+
+// runTests runs every top-level test* function in the *_test.lox files
+// under each path, each in a fresh interpreter, and reports the results as
+// TAP or JUnit XML. The exit status is 1 if any test failed.
+func runTests(arguments []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	format := flags.String("format", "tap", "output format: tap or junit")
+	flags.Parse(arguments)
+	if *format != "tap" && *format != "junit" {
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *format)
+		os.Exit(64)
+	}
+
+	if flags.NArg() == 0 {
+		usage()
+	}
+	files, err := findTestFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var results []testResult
+	for _, file := range files {
+		results = append(results, runTestFile(ctx, file)...)
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
+	}
+
+	if *format == "junit" {
+		writeJUnit(os.Stdout, results)
+	} else {
+		writeTAP(os.Stdout, results)
+	}
+	for _, result := range results {
+		if !result.passed() {
+			os.Exit(1)
+		}
+	}
+}
+
+func findTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(file, "_test.lox") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func runTestFile(ctx context.Context, file string) []testResult {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return []testResult{{file: file, err: err}}
+	}
+	if diagnostics := lox.Check(string(source)); len(diagnostics) > 0 {
+		return []testResult{{file: file, err: errors.Join(diagnostics...)}}
+	}
+
+	var results []testResult
+	for _, name := range testNames(string(source)) {
+		results = append(results, runTest(ctx, file, string(source), name))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return results
+}
+
+// testNames lists the top-level functions whose names start with "test",
+// in source order.
+func testNames(source string) []string {
+	tokens, _ := lox.NewScanner(source).ScanTokens()
+	statements, _ := lox.NewParser(tokens).Parse()
+
+	var names []string
+	for _, statement := range statements {
+		if function, ok := statement.(*lox.Function); ok && strings.HasPrefix(function.Name.Lexeme, "test") {
+			names = append(names, function.Name.Lexeme)
+		}
+	}
+	return names
+}
+
+func runTest(ctx context.Context, file, source, name string) testResult {
+	var output bytes.Buffer
+	interpreter := lox.New(lox.Options{
+		Stdout:       &output,
+		Capabilities: lox.CapAll,
+	})
+	defer interpreter.Close()
+	installAssertions(interpreter)
+
+	start := time.Now()
+	err := interpreter.RunContext(ctx, source)
+	if err == nil {
+		test, _ := interpreter.Global(name)
+		_, err = interpreter.CallContext(ctx, test)
+	}
+
+	result := testResult{file: file, name: name, err: err, output: output.String(), duration: time.Since(start)}
+	var runtimeErr *lox.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		result.line = runtimeErr.Token().Line
+	}
+	return result
+}
+
+// installAssertions defines the assert, assertEqual and assertThrows
+// natives. A failed assertion is a runtime error reported at its call.
+func installAssertions(interpreter *lox.Interpreter) {
+	failure := func(message *string, fallback string) error {
+		if message != nil {
+			return errors.New(*message)
+		}
+		return errors.New(fallback)
+	}
+
+	interpreter.RegisterFunc("assert", func(condition interface{}, message *string) error {
+		if condition == nil || condition == false {
+			return failure(message, "Assertion failed.")
+		}
+		return nil
+	})
+	interpreter.RegisterFunc("assertEqual", func(actual, expected interface{}, message *string) error {
+		if !lox.NewValue(actual).Equal(lox.NewValue(expected)) {
+			return failure(message, fmt.Sprintf("Expected %s but got %s.", lox.Stringify(expected), lox.Stringify(actual)))
+		}
+		return nil
+	})
+	// assertThrows calls fn and returns the message of the runtime error it
+	// raises. Interrupts and exceeded limits are not the error under test,
+	// so they still stop the run.
+	interpreter.RegisterFunc("assertThrows", func(fn interface{}, message *string) (string, error) {
+		_, err := interpreter.Call(fn)
+		if err == nil {
+			return "", failure(message, "Expected an error but none was raised.")
+		}
+		if errors.Is(err, lox.ErrInterrupted) || errors.Is(err, lox.ErrDeadlineExceeded) || errors.Is(err, lox.ErrLimitExceeded) {
+			return "", err
+		}
+		var runtimeErr *lox.RuntimeError
+		if errors.As(err, &runtimeErr) {
+			return runtimeErr.Message(), nil
+		}
+		return "", err
+	})
+}
+
+// writeTAP writes results in Test Anything Protocol version 13, with the
+// failure details in a YAML block.
+func writeTAP(out io.Writer, results []testResult) {
+	fmt.Fprintln(out, "TAP version 13")
+	fmt.Fprintf(out, "1..%d\n", len(results))
+	for index, result := range results {
+		if result.passed() {
+			fmt.Fprintf(out, "ok %d - %s\n", index+1, result.title())
+			continue
+		}
+		fmt.Fprintf(out, "not ok %d - %s\n", index+1, result.title())
+		fmt.Fprintln(out, "  ---")
+		fmt.Fprintf(out, "  message: %q\n", failureMessage(result.err))
+		fmt.Fprintf(out, "  file: %q\n", result.file)
+		if result.line > 0 {
+			fmt.Fprintf(out, "  line: %d\n", result.line)
+		}
+		if result.output != "" {
+			fmt.Fprintf(out, "  output: %q\n", result.output)
+		}
+		fmt.Fprintln(out, "  ...")
+	}
+}
+
+// failureMessage drops the "[line N]" suffix of runtime errors, since the
+// line is reported separately.
+func failureMessage(err error) string {
+	var runtimeErr *lox.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr.Message()
+	}
+	return err.Error()
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes results as JUnit XML with one test suite per file.
+func writeJUnit(out io.Writer, results []testResult) {
+	var suites junitSuites
+	var suiteTime time.Duration
+	for _, result := range results {
+		if len(suites.Suites) == 0 || suites.Suites[len(suites.Suites)-1].Name != result.file {
+			suites.Suites = append(suites.Suites, junitSuite{Name: result.file})
+			suiteTime = 0
+		}
+		suite := &suites.Suites[len(suites.Suites)-1]
+
+		testCase := junitCase{
+			Name:      result.name,
+			ClassName: strings.TrimSuffix(filepath.ToSlash(result.file), ".lox"),
+			Time:      seconds(result.duration),
+			SystemOut: result.output,
+		}
+		if testCase.Name == "" {
+			testCase.Name = "(load)"
+		}
+		if !result.passed() {
+			location := result.file
+			if result.line > 0 {
+				location = fmt.Sprintf("%s:%d", result.file, result.line)
+			}
+			testCase.Failure = &junitFailure{
+				Message: failureMessage(result.err),
+				Text:    fmt.Sprintf("%s: %s", location, failureMessage(result.err)),
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suiteTime += result.duration
+		suite.Time = seconds(suiteTime)
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	fmt.Fprint(out, xml.Header)
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	encoder.Encode(suites)
+	fmt.Fprintln(out)
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
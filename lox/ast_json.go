@@ -0,0 +1,552 @@
+package lox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalAST encodes a program as JSON. Every node is an object with a
+// "kind" naming its Go type, the "line" and "column" of the token it is
+// reported at, the "start" and "end" byte offsets of its span, and one key
+// per child. Expression statements and nodes synthesized by the parser have
+// no token of their own and are encoded without a position. Tokens are encoded in full, apart from the comments and
+// whitespace before them, so UnmarshalAST can rebuild the same tree.
+func MarshalAST(statements []Stmt) ([]byte, error) {
+	encoder := astEncoder{}
+	return json.Marshal(jsonObject{
+		{"kind", "Program"},
+		{"statements", encoder.stmts(statements)},
+	})
+}
+
+// UnmarshalAST decodes a program produced by MarshalAST.
+func UnmarshalAST(data []byte) ([]Stmt, error) {
+	var program struct {
+		Kind       string            `json:"kind"`
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal(data, &program); err != nil {
+		return nil, err
+	}
+	if program.Kind != "Program" {
+		return nil, fmt.Errorf("expected a Program node, got %q", program.Kind)
+	}
+	return decodeStmts(program.Statements)
+}
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject is a JSON object that keeps its keys in insertion order, so
+// "kind" and the position come first.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for index, field := range o {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func (o jsonObject) get(key string) (interface{}, bool) {
+	for _, field := range o {
+		if field.key == key {
+			return field.value, true
+		}
+	}
+	return nil, false
+}
+
+type astEncoder struct{}
+
+// node builds a node object positioned at token. Nodes without a token of
+// their own, such as expression statements, and synthesized ones have no
+// position.
+func (a astEncoder) node(kind string, token *Token, fields ...jsonField) jsonObject {
+	node := jsonObject{{"kind", kind}}
+	if token != nil && token.Line > 0 {
+		node = append(node, jsonField{"line", token.Line}, jsonField{"column", token.Column})
+	}
+	return append(node, fields...)
+}
+
+// spanned adds the span's offsets after the node's position.
+func (a astEncoder) spanned(node jsonObject, span Span) jsonObject {
+	header := 1
+	if _, ok := node.get("line"); ok {
+		header = 3
+	}
+	fields := append(jsonObject{}, node[:header]...)
+	fields = append(fields, jsonField{"start", span.Start}, jsonField{"end", span.End})
+	return append(fields, node[header:]...)
+}
+
+func (a astEncoder) token(token Token) jsonObject {
+	return jsonObject{
+		{"type", token.Type},
+		{"lexeme", token.Lexeme},
+		{"literal", token.Literal},
+		{"line", token.Line},
+		{"column", token.Column},
+		{"start", token.Start},
+		{"end", token.End},
+	}
+}
+
+func (a astEncoder) tokens(tokens []Token) []jsonObject {
+	encoded := make([]jsonObject, len(tokens))
+	for index, token := range tokens {
+		encoded[index] = a.token(token)
+	}
+	return encoded
+}
+
+func (a astEncoder) expr(expr Expr) interface{} {
+	if expr == nil {
+		return nil
+	}
+	return a.spanned(expr.Accept(a).(jsonObject), expr.Span())
+}
+
+func (a astEncoder) exprs(exprs []Expr) []jsonObject {
+	encoded := make([]jsonObject, len(exprs))
+	for index, expr := range exprs {
+		encoded[index] = a.spanned(expr.Accept(a).(jsonObject), expr.Span())
+	}
+	return encoded
+}
+
+func (a astEncoder) stmt(stmt Stmt) interface{} {
+	if stmt == nil {
+		return nil
+	}
+	return a.spanned(stmt.Accept(a).(jsonObject), stmt.Span())
+}
+
+func (a astEncoder) stmts(stmts []Stmt) []jsonObject {
+	encoded := make([]jsonObject, len(stmts))
+	for index, stmt := range stmts {
+		encoded[index] = a.spanned(stmt.Accept(a).(jsonObject), stmt.Span())
+	}
+	return encoded
+}
+
+func (a astEncoder) VisitBinaryExpr(expr *Binary) interface{} {
+	return a.node("Binary", &expr.Operator,
+		jsonField{"left", a.expr(expr.Left)},
+		jsonField{"operator", a.token(expr.Operator)},
+		jsonField{"right", a.expr(expr.Right)})
+}
+
+func (a astEncoder) VisitLiteralExpr(expr *Literal) interface{} {
+	return a.node("Literal", &expr.Token,
+		jsonField{"value", expr.Value},
+		jsonField{"token", a.token(expr.Token)})
+}
+
+func (a astEncoder) VisitGroupingExpr(expr *Grouping) interface{} {
+	return a.node("Grouping", &expr.Paren,
+		jsonField{"paren", a.token(expr.Paren)},
+		jsonField{"expression", a.expr(expr.Expression)})
+}
+
+func (a astEncoder) VisitUnaryExpr(expr *Unary) interface{} {
+	return a.node("Unary", &expr.Operator,
+		jsonField{"operator", a.token(expr.Operator)},
+		jsonField{"right", a.expr(expr.Right)})
+}
+
+func (a astEncoder) VisitVariableExpr(expr *Variable) interface{} {
+	return a.node("Variable", &expr.Name,
+		jsonField{"name", a.token(expr.Name)})
+}
+
+func (a astEncoder) VisitAssignExpr(expr *Assign) interface{} {
+	return a.node("Assign", &expr.Name,
+		jsonField{"name", a.token(expr.Name)},
+		jsonField{"value", a.expr(expr.Value)})
+}
+
+func (a astEncoder) VisitLogicalExpr(expr *Logical) interface{} {
+	return a.node("Logical", &expr.Operator,
+		jsonField{"left", a.expr(expr.Left)},
+		jsonField{"operator", a.token(expr.Operator)},
+		jsonField{"right", a.expr(expr.Right)})
+}
+
+func (a astEncoder) VisitCallExpr(expr *Call) interface{} {
+	return a.node("Call", &expr.Paren,
+		jsonField{"callee", a.expr(expr.Callee)},
+		jsonField{"paren", a.token(expr.Paren)},
+		jsonField{"arguments", a.exprs(expr.Arguments)})
+}
+
+func (a astEncoder) VisitFunctionExpr(expr *FunctionExpr) interface{} {
+	return a.node("FunctionExpr", &expr.Name,
+		jsonField{"name", a.token(expr.Name)},
+		jsonField{"params", a.tokens(expr.Params)},
+		jsonField{"body", a.stmts(expr.Body)})
+}
+
+func (a astEncoder) VisitGetExpr(expr *Get) interface{} {
+	return a.node("Get", &expr.Name,
+		jsonField{"object", a.expr(expr.Object)},
+		jsonField{"name", a.token(expr.Name)})
+}
+
+func (a astEncoder) VisitSetExpr(expr *Set) interface{} {
+	return a.node("Set", &expr.Name,
+		jsonField{"object", a.expr(expr.Object)},
+		jsonField{"name", a.token(expr.Name)},
+		jsonField{"value", a.expr(expr.Value)})
+}
+
+func (a astEncoder) VisitThisExpr(expr *This) interface{} {
+	return a.node("This", &expr.Keyword,
+		jsonField{"keyword", a.token(expr.Keyword)})
+}
+
+func (a astEncoder) VisitSuperExpr(expr *Super) interface{} {
+	return a.node("Super", &expr.Keyword,
+		jsonField{"keyword", a.token(expr.Keyword)},
+		jsonField{"method", a.token(expr.Method)})
+}
+
+func (a astEncoder) VisitPrintStmt(stmt *Print) interface{} {
+	return a.node("Print", &stmt.Keyword,
+		jsonField{"keyword", a.token(stmt.Keyword)},
+		jsonField{"expression", a.expr(stmt.Expression)})
+}
+
+func (a astEncoder) VisitExpressionStmt(stmt *Expression) interface{} {
+	return a.node("Expression", nil,
+		jsonField{"expression", a.expr(stmt.Expression)})
+}
+
+func (a astEncoder) VisitVarStmt(stmt *Var) interface{} {
+	return a.node("Var", &stmt.Name,
+		jsonField{"name", a.token(stmt.Name)},
+		jsonField{"initializer", a.expr(stmt.Initializer)})
+}
+
+func (a astEncoder) VisitBlockStmt(stmt *Block) interface{} {
+	return a.node("Block", &stmt.Brace,
+		jsonField{"brace", a.token(stmt.Brace)},
+		jsonField{"statements", a.stmts(stmt.Statements)})
+}
+
+func (a astEncoder) VisitIfStmt(stmt *If) interface{} {
+	return a.node("If", &stmt.Keyword,
+		jsonField{"keyword", a.token(stmt.Keyword)},
+		jsonField{"condition", a.expr(stmt.Condition)},
+		jsonField{"thenBranch", a.stmt(stmt.ThenBranch)},
+		jsonField{"elseBranch", a.stmt(stmt.ElseBranch)})
+}
+
+func (a astEncoder) VisitWhileStmt(stmt *While) interface{} {
+	return a.node("While", &stmt.Keyword,
+		jsonField{"keyword", a.token(stmt.Keyword)},
+		jsonField{"condition", a.expr(stmt.Condition)},
+		jsonField{"body", a.stmt(stmt.Body)})
+}
+
+func (a astEncoder) VisitFunctionStmt(stmt *Function) interface{} {
+	return a.node("Function", &stmt.Name,
+		jsonField{"name", a.token(stmt.Name)},
+		jsonField{"params", a.tokens(stmt.Params)},
+		jsonField{"body", a.stmts(stmt.Body)})
+}
+
+func (a astEncoder) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+	return a.node("ReturnStmt", &stmt.Keyword,
+		jsonField{"keyword", a.token(stmt.Keyword)},
+		jsonField{"value", a.expr(stmt.Value)})
+}
+
+func (a astEncoder) VisitResolverStmt(stmt *Resolver) interface{} {
+	return a.node("Resolver", nil)
+}
+
+func (a astEncoder) VisitClassStmt(stmt *Class) interface{} {
+	return a.node("Class", &stmt.Name,
+		jsonField{"name", a.token(stmt.Name)},
+		jsonField{"superclass", a.expr(stmt.Superclass)},
+		jsonField{"methods", a.stmts(stmt.Methods)})
+}
+
+// jsonNode holds a node's fields until its kind says how to read them.
+type jsonNode map[string]json.RawMessage
+
+func readNode(raw json.RawMessage) (jsonNode, string, error) {
+	if isNull(raw) {
+		return nil, "", nil
+	}
+	var node jsonNode
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, "", err
+	}
+	var kind string
+	if err := json.Unmarshal(node["kind"], &kind); err != nil {
+		return nil, "", fmt.Errorf("node without a kind: %s", raw)
+	}
+	return node, kind, nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func (n jsonNode) token(key string) (Token, error) {
+	var token Token
+	if isNull(n[key]) {
+		return token, nil
+	}
+	var encoded struct {
+		Type    TokenType   `json:"type"`
+		Lexeme  string      `json:"lexeme"`
+		Literal interface{} `json:"literal"`
+		Line    int         `json:"line"`
+		Column  int         `json:"column"`
+		Start   int         `json:"start"`
+		End     int         `json:"end"`
+	}
+	if err := json.Unmarshal(n[key], &encoded); err != nil {
+		return token, fmt.Errorf("%s: %w", key, err)
+	}
+	token = NewToken(encoded.Type, encoded.Lexeme, encoded.Literal, encoded.Line)
+	token.Column = encoded.Column
+	token.Start = encoded.Start
+	token.End = encoded.End
+	return token, nil
+}
+
+func (n jsonNode) tokens(key string) ([]Token, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(n[key], &raws); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	tokens := make([]Token, len(raws))
+	for index, raw := range raws {
+		token, err := jsonNode{"token": raw}.token("token")
+		if err != nil {
+			return nil, err
+		}
+		tokens[index] = token
+	}
+	return tokens, nil
+}
+
+func (n jsonNode) expr(key string) (Expr, error) {
+	return decodeExpr(n[key])
+}
+
+func (n jsonNode) exprs(key string) ([]Expr, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(n[key], &raws); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	// Like the parser, leave an empty argument list nil.
+	var exprs []Expr
+	for _, raw := range raws {
+		expr, err := decodeExpr(raw)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+func (n jsonNode) stmt(key string) (Stmt, error) {
+	return decodeStmt(n[key])
+}
+
+func (n jsonNode) stmts(key string) ([]Stmt, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(n[key], &raws); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return decodeStmts(raws)
+}
+
+func decodeStmts(raws []json.RawMessage) ([]Stmt, error) {
+	stmts := make([]Stmt, len(raws))
+	for index, raw := range raws {
+		stmt, err := decodeStmt(raw)
+		if err != nil {
+			return nil, err
+		}
+		stmts[index] = stmt
+	}
+	return stmts, nil
+}
+
+// fieldDecoder collects the first error from a run of field reads so each
+// node can be decoded in one expression.
+type fieldDecoder struct {
+	node jsonNode
+	err  error
+}
+
+func (d *fieldDecoder) token(key string) Token {
+	token, err := d.node.token(key)
+	d.keep(err)
+	return token
+}
+
+func (d *fieldDecoder) tokens(key string) []Token {
+	tokens, err := d.node.tokens(key)
+	d.keep(err)
+	return tokens
+}
+
+func (d *fieldDecoder) expr(key string) Expr {
+	expr, err := d.node.expr(key)
+	d.keep(err)
+	return expr
+}
+
+func (d *fieldDecoder) exprs(key string) []Expr {
+	exprs, err := d.node.exprs(key)
+	d.keep(err)
+	return exprs
+}
+
+func (d *fieldDecoder) stmt(key string) Stmt {
+	stmt, err := d.node.stmt(key)
+	d.keep(err)
+	return stmt
+}
+
+func (d *fieldDecoder) stmts(key string) []Stmt {
+	stmts, err := d.node.stmts(key)
+	d.keep(err)
+	return stmts
+}
+
+func (d *fieldDecoder) span() Spanned {
+	return Spanned{span: Span{Start: d.offset("start"), End: d.offset("end")}}
+}
+
+func (d *fieldDecoder) offset(key string) int {
+	var offset int
+	if !isNull(d.node[key]) {
+		d.keep(json.Unmarshal(d.node[key], &offset))
+	}
+	return offset
+}
+
+func (d *fieldDecoder) value(key string) interface{} {
+	var value interface{}
+	if !isNull(d.node[key]) {
+		d.keep(json.Unmarshal(d.node[key], &value))
+	}
+	return value
+}
+
+func (d *fieldDecoder) keep(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func decodeExpr(raw json.RawMessage) (Expr, error) {
+	node, kind, err := readNode(raw)
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	d := &fieldDecoder{node: node}
+	var expr Expr
+	switch kind {
+	case "Binary":
+		expr = &Binary{Spanned: d.span(), Left: d.expr("left"), Operator: d.token("operator"), Right: d.expr("right")}
+	case "Literal":
+		expr = &Literal{Spanned: d.span(), Value: d.value("value"), Token: d.token("token")}
+	case "Grouping":
+		expr = &Grouping{Spanned: d.span(), Paren: d.token("paren"), Expression: d.expr("expression")}
+	case "Unary":
+		expr = &Unary{Spanned: d.span(), Operator: d.token("operator"), Right: d.expr("right")}
+	case "Variable":
+		expr = &Variable{Spanned: d.span(), Name: d.token("name")}
+	case "Assign":
+		expr = &Assign{Spanned: d.span(), Name: d.token("name"), Value: d.expr("value")}
+	case "Logical":
+		expr = &Logical{Spanned: d.span(), Left: d.expr("left"), Operator: d.token("operator"), Right: d.expr("right")}
+	case "Call":
+		expr = &Call{Spanned: d.span(), Callee: d.expr("callee"), Paren: d.token("paren"), Arguments: d.exprs("arguments")}
+	case "FunctionExpr":
+		expr = &FunctionExpr{Spanned: d.span(), Name: d.token("name"), Params: d.tokens("params"), Body: d.stmts("body")}
+	case "Get":
+		expr = &Get{Spanned: d.span(), Object: d.expr("object"), Name: d.token("name")}
+	case "Set":
+		expr = &Set{Spanned: d.span(), Object: d.expr("object"), Name: d.token("name"), Value: d.expr("value")}
+	case "This":
+		expr = &This{Spanned: d.span(), Keyword: d.token("keyword")}
+	case "Super":
+		expr = &Super{Spanned: d.span(), Keyword: d.token("keyword"), Method: d.token("method")}
+	default:
+		return nil, fmt.Errorf("unknown expression kind %q", kind)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", kind, d.err)
+	}
+	return expr, nil
+}
+
+func decodeStmt(raw json.RawMessage) (Stmt, error) {
+	node, kind, err := readNode(raw)
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	d := &fieldDecoder{node: node}
+	var stmt Stmt
+	switch kind {
+	case "Print":
+		stmt = &Print{Spanned: d.span(), Keyword: d.token("keyword"), Expression: d.expr("expression")}
+	case "Expression":
+		stmt = &Expression{Spanned: d.span(), Expression: d.expr("expression")}
+	case "Var":
+		stmt = &Var{Spanned: d.span(), Name: d.token("name"), Initializer: d.expr("initializer")}
+	case "Block":
+		stmt = &Block{Spanned: d.span(), Brace: d.token("brace"), Statements: d.stmts("statements")}
+	case "If":
+		stmt = &If{Spanned: d.span(), Keyword: d.token("keyword"), Condition: d.expr("condition"), ThenBranch: d.stmt("thenBranch"), ElseBranch: d.stmt("elseBranch")}
+	case "While":
+		stmt = &While{Spanned: d.span(), Keyword: d.token("keyword"), Condition: d.expr("condition"), Body: d.stmt("body")}
+	case "Function":
+		stmt = &Function{Spanned: d.span(), Name: d.token("name"), Params: d.tokens("params"), Body: d.stmts("body")}
+	case "ReturnStmt":
+		stmt = &ReturnStmt{Spanned: d.span(), Keyword: d.token("keyword"), Value: d.expr("value")}
+	case "Class":
+		stmt = &Class{Spanned: d.span(), Name: d.token("name"), Superclass: d.expr("superclass"), Methods: d.stmts("methods")}
+	case "Resolver":
+		stmt = NewResolver()
+	default:
+		return nil, fmt.Errorf("unknown statement kind %q", kind)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", kind, d.err)
+	}
+	return stmt, nil
+}
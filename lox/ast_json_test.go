@@ -0,0 +1,195 @@
+package lox
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// everyNode is a program with at least one node of each kind the parser
+// produces.
+const everyNode = `var a = 1 + 2 * -3;
+var b = (a >= 2) == !false;
+var c = nil;
+var s = "text";
+a = a - 1;
+print a or b and c;
+{
+  var local = s;
+  print local;
+}
+if (a < 0) print "negative"; else print "other";
+while (a > 0) a = a - 1;
+for (var i = 0; i < 2; i = i + 1) print i;
+fun add(x, y) {
+  return x + y;
+}
+fun maker() {
+  return fun inner(z) { return z; };
+}
+class Base {
+  init(value) {
+    this.value = value;
+  }
+  get() {
+    return this.value;
+  }
+}
+class Derived < Base {
+  get() {
+    return super.get() + 1;
+  }
+}
+print Derived(add(1, 2)).get();
+maker()(true);
+`
+
+func TestASTRoundTrip(t *testing.T) {
+	statements, err := parseProgram(everyNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parser never produces a Resolver, but it is a statement and must
+	// survive the round trip too.
+	statements = append(statements, NewResolver())
+
+	kinds := map[string]bool{}
+	collectKinds(reflect.ValueOf(statements), kinds)
+	for _, kind := range []string{
+		"Binary", "Literal", "Grouping", "Unary", "Variable", "Assign", "Logical", "Call",
+		"FunctionExpr", "Get", "Set", "This", "Super",
+		"Print", "Expression", "Var", "Block", "If", "While", "Function", "ReturnStmt", "Class", "Resolver",
+	} {
+		if !kinds[kind] {
+			t.Errorf("test program has no %s node", kind)
+		}
+	}
+
+	encoded, err := MarshalAST(statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalAST(encoded)
+	if err != nil {
+		t.Fatalf("UnmarshalAST: %v", err)
+	}
+	// Comments and whitespace are left out of the encoding.
+	stripTrivia(reflect.ValueOf(statements))
+	if !reflect.DeepEqual(decoded, statements) {
+		printer := AstPrinter{}
+		t.Errorf("round trip changed the tree:\n got: %s\nwant: %s", printer.PrintProgram(decoded), printer.PrintProgram(statements))
+	}
+
+	reencoded, err := MarshalAST(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reencoded) != string(encoded) {
+		t.Errorf("re-encoding differs:\n got: %s\nwant: %s", reencoded, encoded)
+	}
+}
+
+func TestMarshalASTPositions(t *testing.T) {
+	statements, err := parseProgram("print 1+2;\n  { (x); }\nif (x) x;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := MarshalAST(statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var program struct {
+		Statements []map[string]interface{} `json:"statements"`
+	}
+	if err := json.Unmarshal(encoded, &program); err != nil {
+		t.Fatal(err)
+	}
+
+	position := func(node map[string]interface{}) []interface{} {
+		return []interface{}{node["kind"], node["line"], node["column"]}
+	}
+	block := program.Statements[1]
+	expression := block["statements"].([]interface{})[0].(map[string]interface{})
+	for _, test := range []struct {
+		node map[string]interface{}
+		want []interface{}
+	}{
+		{program.Statements[0], []interface{}{"Print", 1.0, 1.0}},
+		{block, []interface{}{"Block", 2.0, 3.0}},
+		{expression, []interface{}{"Expression", nil, nil}},
+		{expression["expression"].(map[string]interface{}), []interface{}{"Grouping", 2.0, 5.0}},
+		{program.Statements[2], []interface{}{"If", 3.0, 1.0}},
+	} {
+		if got := position(test.node); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("position = %v, want %v", got, test.want)
+		}
+	}
+}
+
+func TestUnmarshalASTRejectsUnknownKinds(t *testing.T) {
+	for _, data := range []string{
+		`{"kind": "Statements", "statements": []}`,
+		`{"kind": "Program", "statements": [{"kind": "Goto"}]}`,
+		`{"kind": "Program", "statements": [{"kind": "Print", "expression": {"kind": "Ternary"}}]}`,
+	} {
+		if _, err := UnmarshalAST([]byte(data)); err == nil {
+			t.Errorf("UnmarshalAST(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+// collectKinds records the type name of every syntax tree node reachable
+// from value.
+func collectKinds(value reflect.Value, kinds map[string]bool) {
+	switch value.Kind() {
+	case reflect.Interface:
+		if !value.IsNil() {
+			collectKinds(value.Elem(), kinds)
+		}
+	case reflect.Pointer:
+		if value.IsNil() {
+			return
+		}
+		if _, ok := value.Interface().(Stmt); ok {
+			kinds[value.Elem().Type().Name()] = true
+		} else if _, ok := value.Interface().(Expr); ok {
+			kinds[value.Elem().Type().Name()] = true
+		}
+		collectKinds(value.Elem(), kinds)
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			if value.Type().Field(index).IsExported() {
+				collectKinds(value.Field(index), kinds)
+			}
+		}
+	case reflect.Slice:
+		for index := 0; index < value.Len(); index++ {
+			collectKinds(value.Index(index), kinds)
+		}
+	}
+}
+
+// stripTrivia clears the comments and whitespace recorded on every token
+// reachable from value.
+func stripTrivia(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if !value.IsNil() {
+			stripTrivia(value.Elem())
+		}
+	case reflect.Struct:
+		if token, ok := value.Addr().Interface().(*Token); ok {
+			token.Leading = nil
+			return
+		}
+		for index := 0; index < value.NumField(); index++ {
+			if value.Type().Field(index).IsExported() {
+				stripTrivia(value.Field(index))
+			}
+		}
+	case reflect.Slice:
+		for index := 0; index < value.Len(); index++ {
+			stripTrivia(value.Index(index))
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AstPrinter struct{}
+
+func (a *AstPrinter) Print(expr Expr) string {
+	return expr.Accept(a).(string)
+}
+
+// PrintStmt renders a statement as an S-expression on one line.
+func (a *AstPrinter) PrintStmt(stmt Stmt) string {
+	return stmt.Accept(a).(string)
+}
+
+// PrintProgram renders each top-level statement on its own line.
+func (a *AstPrinter) PrintProgram(statements []Stmt) string {
+	lines := make([]string, len(statements))
+	for index, stmt := range statements {
+		lines[index] = a.PrintStmt(stmt)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (a *AstPrinter) VisitVariableExpr(expr *Variable) interface{} {
+	return expr.Name.Lexeme
+}
+
+func (a *AstPrinter) VisitUnaryExpr(expr *Unary) interface{} {
+	return a.parenthesize(expr.Operator.Lexeme, expr.Right)
+}
+
+func (a *AstPrinter) VisitBinaryExpr(expr *Binary) interface{} {
+	return a.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (a *AstPrinter) VisitLiteralExpr(expr *Literal) interface{} {
+	if expr.Value == nil {
+		return "nil"
+	}
+	switch v := expr.Value.(type) {
+	case string:
+		return v
+	case float64:
+		str := fmt.Sprintf("%v", v)
+		if !strings.Contains(str, ".") {
+			return fmt.Sprintf("%.1f", v)
+		}
+		return str
+	default:
+		return fmt.Sprintf("%v", expr.Value)
+	}
+}
+
+func (a *AstPrinter) VisitGroupingExpr(expr *Grouping) interface{} {
+	return a.parenthesize("group", expr.Expression)
+}
+
+func (a *AstPrinter) parenthesize(name string, exprs ...Expr) string {
+	var result string
+	result += "(" + name
+	for _, expr := range exprs {
+		result += " "
+		result += expr.Accept(a).(string)
+	}
+	result += ")"
+	return result
+}
+
+func (a *AstPrinter) VisitAssignExpr(expr *Assign) interface{} {
+	return a.parenthesize("=", &Variable{Name: expr.Name}, expr.Value)
+}
+
+func (a *AstPrinter) VisitLogicalExpr(expr *Logical) interface{} {
+	return a.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (a *AstPrinter) VisitCallExpr(expr *Call) interface{} {
+	calleeStr := expr.Callee.Accept(a).(string)
+
+	var allExprs []Expr
+	allExprs = append(allExprs, expr.Arguments...)
+
+	return a.parenthesize(calleeStr, allExprs...)
+}
+
+func (a *AstPrinter) VisitFunctionExpr(expr *FunctionExpr) interface{} {
+	var builder strings.Builder
+
+	if expr.Name.Lexeme != "" {
+		builder.WriteString(expr.Name.Lexeme)
+	} else {
+		builder.WriteString("anonymous")
+	}
+
+	builder.WriteString("(")
+
+	for i, param := range expr.Params {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(param.Lexeme)
+	}
+
+	builder.WriteString(") ")
+	builder.WriteString("{ ... }")
+
+	return builder.String()
+}
+
+func (a *AstPrinter) VisitGetExpr(expr *Get) interface{} {
+	objectStr := expr.Object.Accept(a).(string)
+	return fmt.Sprintf("%s.%s", objectStr, expr.Name.Lexeme)
+}
+
+func (a *AstPrinter) VisitSetExpr(expr *Set) interface{} {
+	objectStr := expr.Object.Accept(a).(string)
+	valueStr := expr.Value.Accept(a).(string)
+	return fmt.Sprintf("(%s.%s = %s)", objectStr, expr.Name.Lexeme, valueStr)
+}
+
+func (a *AstPrinter) VisitThisExpr(expr *This) interface{} {
+	return "this"
+}
+
+func (a *AstPrinter) VisitSuperExpr(expr *Super) interface{} {
+	return fmt.Sprintf("super.%s", expr.Method.Lexeme)
+}
+
+func (a *AstPrinter) VisitPrintStmt(stmt *Print) interface{} {
+	return a.parenthesize("print", stmt.Expression)
+}
+
+func (a *AstPrinter) VisitExpressionStmt(stmt *Expression) interface{} {
+	return a.parenthesize(";", stmt.Expression)
+}
+
+func (a *AstPrinter) VisitVarStmt(stmt *Var) interface{} {
+	if stmt.Initializer == nil {
+		return a.parenthesizeParts("var", stmt.Name)
+	}
+	return a.parenthesizeParts("var", stmt.Name, "=", stmt.Initializer)
+}
+
+func (a *AstPrinter) VisitBlockStmt(stmt *Block) interface{} {
+	return a.parenthesizeParts("block", stmt.Statements)
+}
+
+func (a *AstPrinter) VisitIfStmt(stmt *If) interface{} {
+	if stmt.ElseBranch == nil {
+		return a.parenthesizeParts("if", stmt.Condition, stmt.ThenBranch)
+	}
+	return a.parenthesizeParts("if-else", stmt.Condition, stmt.ThenBranch, stmt.ElseBranch)
+}
+
+func (a *AstPrinter) VisitWhileStmt(stmt *While) interface{} {
+	return a.parenthesizeParts("while", stmt.Condition, stmt.Body)
+}
+
+func (a *AstPrinter) VisitFunctionStmt(stmt *Function) interface{} {
+	params := make([]string, len(stmt.Params))
+	for index, param := range stmt.Params {
+		params[index] = param.Lexeme
+	}
+	signature := fmt.Sprintf("%s(%s)", stmt.Name.Lexeme, strings.Join(params, " "))
+	return a.parenthesizeParts("fun", signature, stmt.Body)
+}
+
+func (a *AstPrinter) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return a.parenthesize("return", stmt.Value)
+}
+
+func (a *AstPrinter) VisitResolverStmt(stmt *Resolver) interface{} {
+	return "(resolver)"
+}
+
+func (a *AstPrinter) VisitClassStmt(stmt *Class) interface{} {
+	if stmt.Superclass == nil {
+		return a.parenthesizeParts("class", stmt.Name, stmt.Methods)
+	}
+	return a.parenthesizeParts("class", stmt.Name, "<", stmt.Superclass, stmt.Methods)
+}
+
+// parenthesizeParts is parenthesize for nodes that mix expressions,
+// statements, tokens and plain words.
+func (a *AstPrinter) parenthesizeParts(name string, parts ...interface{}) string {
+	var builder strings.Builder
+	builder.WriteString("(" + name)
+	for _, part := range parts {
+		switch part := part.(type) {
+		case Expr:
+			builder.WriteString(" " + part.Accept(a).(string))
+		case Stmt:
+			builder.WriteString(" " + part.Accept(a).(string))
+		case []Stmt:
+			for _, stmt := range part {
+				builder.WriteString(" " + stmt.Accept(a).(string))
+			}
+		case Token:
+			builder.WriteString(" " + part.Lexeme)
+		case string:
+			builder.WriteString(" " + part)
+		}
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
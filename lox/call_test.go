@@ -0,0 +1,158 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const callScript = `
+fun add(a, b) { return a + b; }
+fun fail(message) { return message - 1; }
+fun spin() { while (true) {} }
+class Counter {
+  init(start) { this.count = start; }
+  add(n) { this.count = this.count + n; return this.count; }
+  broken() { return this.missing; }
+}
+var counter = Counter(10);
+counter.callback = add;
+`
+
+func newCallInterpreter(t *testing.T) *Interpreter {
+	t.Helper()
+	interpreter := New(Options{Stdout: &bytes.Buffer{}})
+	if err := interpreter.Run(callScript); err != nil {
+		t.Fatal(err)
+	}
+	return interpreter
+}
+
+func global(t *testing.T, interpreter *Interpreter, name string) Value {
+	t.Helper()
+	value, ok := interpreter.Global(name)
+	if !ok {
+		t.Fatalf("no global %s", name)
+	}
+	return value
+}
+
+func TestCallFunctionsFromGo(t *testing.T) {
+	interpreter := newCallInterpreter(t)
+
+	sum, err := interpreter.Call(global(t, interpreter, "add"), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := sum.Number(); n != 3 {
+		t.Errorf("add(1, 2) = %v, want 3", sum)
+	}
+
+	joined, err := interpreter.Call(global(t, interpreter, "add").Interface(), "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := joined.Str(); s != "ab" {
+		t.Errorf(`add("a", "b") = %v, want "ab"`, joined)
+	}
+
+	instance, err := interpreter.Call(global(t, interpreter, "Counter"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := instance.Interface().(*LoxInstance); !ok {
+		t.Errorf("Counter(5) = %v, want an instance", instance)
+	}
+}
+
+func TestCallArityAndTypeErrors(t *testing.T) {
+	interpreter := newCallInterpreter(t)
+	add := global(t, interpreter, "add")
+
+	for _, test := range []struct {
+		args []interface{}
+		want string
+	}{
+		{[]interface{}{1}, "Expected 2 arguments but got 1."},
+		{[]interface{}{1, 2, 3}, "Expected 2 arguments but got 3."},
+	} {
+		_, err := interpreter.Call(add, test.args...)
+		if got := runtimeMessage(t, err); got != test.want {
+			t.Errorf("add%v: error = %q, want %q", test.args, got, test.want)
+		}
+	}
+
+	if _, err := interpreter.Call(global(t, interpreter, "counter"), 1); err == nil {
+		t.Error("calling an instance succeeded, want an error")
+	}
+	if _, err := interpreter.Call(add, 1, make(chan int)); err == nil {
+		t.Error("calling with an unconvertible argument succeeded, want an error")
+	}
+}
+
+func TestCallReportsRuntimeErrors(t *testing.T) {
+	interpreter := newCallInterpreter(t)
+
+	_, err := interpreter.Call(global(t, interpreter, "fail"), "oops")
+	if got, want := runtimeMessage(t, err), "Operands must be numbers."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+
+	// The interpreter is still usable after an error.
+	if _, err := interpreter.Call(global(t, interpreter, "add"), 1, 1); err != nil {
+		t.Errorf("call after an error: %v", err)
+	}
+}
+
+func TestCallContextStopsCallee(t *testing.T) {
+	interpreter := newCallInterpreter(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := interpreter.CallContext(ctx, global(t, interpreter, "spin"))
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Errorf("error = %v, want ErrDeadlineExceeded", err)
+	}
+}
+
+func TestInvokeMethods(t *testing.T) {
+	interpreter := newCallInterpreter(t)
+	counter := global(t, interpreter, "counter").Interface().(*LoxInstance)
+
+	count, err := counter.Invoke("add", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := count.Number(); n != 15 {
+		t.Errorf("counter.add(5) = %v, want 15", count)
+	}
+
+	// A callable stored in a field is invoked too.
+	sum, err := counter.Invoke("callback", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := sum.Number(); n != 5 {
+		t.Errorf("counter.callback(2, 3) = %v, want 5", sum)
+	}
+
+	for name, want := range map[string]string{
+		"missing": "Undefined property 'missing'.",
+		"broken":  "Undefined property 'missing'.",
+	} {
+		_, err := counter.Invoke(name)
+		if got := runtimeMessage(t, err); got != want {
+			t.Errorf("Invoke(%q): error = %q, want %q", name, got, want)
+		}
+	}
+	_, err = counter.Invoke("add")
+	if got, want := runtimeMessage(t, err), "Expected 1 arguments but got 0."; got != want {
+		t.Errorf("Invoke(\"add\"): error = %q, want %q", got, want)
+	}
+
+	if _, err := NewLoxInstance(counter.class).Invoke("add", 1); err == nil {
+		t.Error("Invoke on a detached instance succeeded, want an error")
+	}
+}
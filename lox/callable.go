@@ -0,0 +1,71 @@
+package lox
+
+import "fmt"
+
+type Callable interface {
+	Call(interpreter *Interpreter, arguments []interface{}) interface{}
+	Arity() int
+}
+
+type LoxCallable interface {
+	Call(interpreter *Interpreter, arguments []interface{}) interface{}
+	Arity() int
+}
+
+// VariadicCallable is implemented by callables that accept a range of
+// argument counts. A negative max means there is no upper bound.
+type VariadicCallable interface {
+	LoxCallable
+	ArityRange() (min, max int)
+}
+
+type NativeFunction struct {
+	name     string
+	function func([]interface{}) interface{}
+	arity    int
+	optional int
+	variadic bool
+}
+
+func (n *NativeFunction) Call(_ *Interpreter, arguments []interface{}) interface{} {
+	return n.function(arguments)
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) ArityRange() (int, int) {
+	if n.variadic {
+		return n.arity, -1
+	}
+	return n.arity, n.arity + n.optional
+}
+
+func (n *NativeFunction) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+func checkArity(function LoxCallable, paren Token, count int) {
+	min, max := function.Arity(), function.Arity()
+	if ranged, ok := function.(VariadicCallable); ok {
+		min, max = ranged.ArityRange()
+	}
+	if count >= min && (max < 0 || count <= max) {
+		return
+	}
+
+	var message string
+	switch {
+	case min == max:
+		message = fmt.Sprintf("Expected %d arguments but got %d.", min, count)
+	case max < 0:
+		message = fmt.Sprintf("Expected at least %d arguments but got %d.", min, count)
+	default:
+		message = fmt.Sprintf("Expected %d to %d arguments but got %d.", min, max, count)
+	}
+	panic(&RuntimeError{
+		token:   paren,
+		message: message,
+	})
+}
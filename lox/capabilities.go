@@ -0,0 +1,227 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Capability is a group of privileged natives that can be installed into an
+// interpreter's globals.
+type Capability uint
+
+const (
+	// CapTime installs clock().
+	CapTime Capability = 1 << iota
+	// CapFS installs readFile, writeFile, fileExists and listDir, confined
+	// to Options.FSRoot.
+	CapFS
+	// CapEnv installs getenv.
+	CapEnv
+	// CapProcess installs exec, which runs a program and returns its output.
+	CapProcess
+	// CapNet installs httpGet.
+	CapNet
+	// CapStdin installs readLine, which reads from Options.Stdin.
+	CapStdin
+
+	// CapAll enables every capability.
+	CapAll = CapTime | CapFS | CapEnv | CapProcess | CapNet | CapStdin
+	// DefaultCapabilities is the harmless set most embedders want; zero
+	// Options.Capabilities installs nothing. It leaves out CapStdin, since
+	// an embedded script reading the host's stdin is rarely intended.
+	DefaultCapabilities = CapTime
+)
+
+func (c Capability) String() string {
+	names := []string{}
+	for _, capability := range []struct {
+		flag Capability
+		name string
+	}{
+		{CapTime, "time"},
+		{CapFS, "fs"},
+		{CapEnv, "env"},
+		{CapProcess, "process"},
+		{CapNet, "net"},
+		{CapStdin, "stdin"},
+	} {
+		if c&capability.flag != 0 {
+			names = append(names, capability.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// AuditEvent describes a call to a privileged native.
+type AuditEvent struct {
+	Capability Capability
+	Name       string
+	Args       []interface{}
+}
+
+func (i *Interpreter) installCapabilities(opts Options) {
+	capabilities := opts.Capabilities
+	i.audit = opts.Audit
+	i.fsRoot = opts.FSRoot
+	if i.fsRoot == "" {
+		i.fsRoot = "."
+	}
+
+	if capabilities&CapTime != 0 {
+		i.registerPrivileged(CapTime, "clock", func() float64 {
+			return float64(time.Now().Unix())
+		})
+	}
+
+	if capabilities&CapStdin != 0 {
+		i.registerPrivileged(CapStdin, "readLine", func() *string {
+			line, err := i.stdin.ReadString('\n')
+			if err != nil && line == "" {
+				return nil
+			}
+			line = strings.TrimRight(line, "\r\n")
+			return &line
+		})
+	}
+	if capabilities&CapFS != 0 {
+		i.registerPrivileged(CapFS, "readFile", func(path string) (string, error) {
+			root, err := i.root()
+			if err != nil {
+				return "", err
+			}
+			file, err := root.Open(path)
+			if err != nil {
+				return "", err
+			}
+			defer file.Close()
+			contents, err := io.ReadAll(file)
+			return string(contents), err
+		})
+		i.registerPrivileged(CapFS, "writeFile", func(path, contents string) error {
+			root, err := i.root()
+			if err != nil {
+				return err
+			}
+			file, err := root.Create(path)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(file, contents); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		})
+		i.registerPrivileged(CapFS, "fileExists", func(path string) (bool, error) {
+			root, err := i.root()
+			if err != nil {
+				return false, err
+			}
+			_, err = root.Stat(path)
+			return err == nil, nil
+		})
+		i.registerPrivileged(CapFS, "listDir", func(path *string) ([]string, error) {
+			root, err := i.root()
+			if err != nil {
+				return nil, err
+			}
+			dir := "."
+			if path != nil {
+				dir = *path
+			}
+			file, err := root.Open(dir)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			return file.Readdirnames(-1)
+		})
+	}
+
+	if capabilities&CapEnv != 0 {
+		i.registerPrivileged(CapEnv, "getenv", func(name string) *string {
+			if value, ok := os.LookupEnv(name); ok {
+				return &value
+			}
+			return nil
+		})
+	}
+
+	if capabilities&CapProcess != 0 {
+		i.registerPrivileged(CapProcess, "exec", func(name string, args ...string) (string, error) {
+			output, err := exec.CommandContext(i.ctx, name, args...).Output()
+			return string(output), err
+		})
+	}
+
+	if capabilities&CapNet != 0 {
+		i.registerPrivileged(CapNet, "httpGet", func(url string) (string, error) {
+			request, err := http.NewRequestWithContext(i.ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return "", err
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				return "", err
+			}
+			defer response.Body.Close()
+			if response.StatusCode >= 400 {
+				return "", fmt.Errorf("GET %s: %s", url, response.Status)
+			}
+			body, err := io.ReadAll(response.Body)
+			return string(body), err
+		})
+	}
+}
+
+// registerPrivileged defines a native that reports each call to the audit
+// hook before running.
+func (i *Interpreter) registerPrivileged(capability Capability, name string, fn interface{}) {
+	native, err := NewNativeFunction(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	call := native.function
+	native.function = func(arguments []interface{}) interface{} {
+		if i.audit != nil {
+			event := AuditEvent{Capability: capability, Name: name, Args: arguments}
+			if err := i.audit(event); err != nil {
+				panic(&RuntimeError{message: err.Error()})
+			}
+		}
+		return call(arguments)
+	}
+	i.globals.Define(name, native)
+}
+
+// root opens the filesystem root on first use. os.Root rejects paths that
+// escape it, including through symlinks.
+func (i *Interpreter) root() (*os.Root, error) {
+	if i.fsRootHandle == nil {
+		root, err := os.OpenRoot(i.fsRoot)
+		if err != nil {
+			return nil, err
+		}
+		i.fsRootHandle = root
+	}
+	return i.fsRootHandle, nil
+}
+
+// Close releases the files the interpreter holds open for filesystem
+// natives. The interpreter stays usable; a later file access reopens them.
+func (i *Interpreter) Close() error {
+	if i.fsRootHandle == nil {
+		return nil
+	}
+	err := i.fsRootHandle.Close()
+	i.fsRootHandle = nil
+	return err
+}
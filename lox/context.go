@@ -0,0 +1,60 @@
+package lox
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrInterrupted is wrapped by the runtime error raised when a script's
+	// context is cancelled.
+	ErrInterrupted = errors.New("lox: execution interrupted")
+	// ErrDeadlineExceeded is wrapped by the runtime error raised when a
+	// script's context deadline or the configured timeout expires.
+	ErrDeadlineExceeded = errors.New("lox: execution deadline exceeded")
+)
+
+// withContext makes ctx, bounded by the configured timeout, the context
+// checked during execution and returns a function restoring the previous one.
+// The outermost entry also starts fresh step and allocation budgets.
+func (i *Interpreter) withContext(ctx context.Context) func() {
+	previous := i.ctx
+	cancel := context.CancelFunc(func() {})
+	if i.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+	}
+	i.ctx = ctx
+	if i.entries == 0 {
+		i.steps = 0
+		i.allocations = 0
+	}
+	i.entries++
+	return func() {
+		i.entries--
+		cancel()
+		i.ctx = previous
+	}
+}
+
+// checkInterrupt raises a runtime error at token once the execution context
+// is done. It is called at every loop iteration and function call.
+func (i *Interpreter) checkInterrupt(token Token) {
+	select {
+	case <-i.ctx.Done():
+	default:
+		return
+	}
+
+	if errors.Is(i.ctx.Err(), context.DeadlineExceeded) {
+		panic(&RuntimeError{
+			token:   token,
+			message: "Execution timed out.",
+			cause:   ErrDeadlineExceeded,
+		})
+	}
+	panic(&RuntimeError{
+		token:   token,
+		message: "Execution interrupted.",
+		cause:   ErrInterrupted,
+	})
+}
@@ -0,0 +1,60 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutStopsRunawayLoop(t *testing.T) {
+	interpreter := New(Options{Timeout: 20 * time.Millisecond})
+	err := interpreter.Run(`while (true) {}`)
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("error = %v, want ErrDeadlineExceeded", err)
+	}
+	if got, want := runtimeMessage(t, err), "Execution timed out."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestCancelledContextInterrupts(t *testing.T) {
+	interpreter := New(Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := interpreter.RunContext(ctx, `fun spin() { while (true) {} } spin();`)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("error = %v, want ErrInterrupted", err)
+	}
+	if got, want := runtimeMessage(t, err), "Execution interrupted."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestInterpreterReusableAfterTimeout(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := New(Options{Stdout: &output, Timeout: 20 * time.Millisecond})
+	if err := interpreter.Run(`var count = 0; while (true) count = count + 1;`); !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("error = %v, want ErrDeadlineExceeded", err)
+	}
+
+	// Each run gets a fresh deadline, and globals from the stopped run are
+	// still there.
+	if err := interpreter.Run(`print count > 0;`); err != nil {
+		t.Fatal(err)
+	}
+	value, err := interpreter.Eval(`1 + 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != "3" {
+		t.Errorf("Eval = %s, want 3", got)
+	}
+	if want := "true\n"; output.String() != want {
+		t.Errorf("output = %q, want %q", output.String(), want)
+	}
+}
@@ -0,0 +1,197 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Diagnostic is an error located in source, in a form DiagnosticRenderer
+// can draw. Scan, parse, resolve and runtime errors all convert to one.
+type Diagnostic struct {
+	Message string
+	// Span is the source the error is about. It is zero when the position
+	// is unknown, such as for errors raised through the embedding API.
+	Span Span
+	// Line is reported when the span cannot be shown against the source.
+	Line int
+	// Filename and Source, when set, are the file the span refers to,
+	// overriding the renderer's. A runtime error raised in a function
+	// loaded from another file carries that file.
+	Filename string
+	Source   string
+	Notes    []string
+	Help     string
+	// anchor is the token the error was reported at. Its lexeme must be
+	// found at its offset for the span to be trusted, since a runtime error
+	// may come from a function loaded with different source.
+	anchor Token
+}
+
+// chunkSource is source run as one chunk, with the name of the file it was
+// read from, if any.
+type chunkSource struct {
+	name string
+	text string
+}
+
+func (s *chunkSource) sourceName() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+func tokenDiagnostic(token Token, message string) Diagnostic {
+	return Diagnostic{
+		Message: message,
+		Span:    Span{Start: token.Start, End: token.End},
+		Line:    token.Line,
+		anchor:  token,
+	}
+}
+
+// Diagnostics converts err, which may join several errors, into
+// diagnostics. Errors of other types become a diagnostic without a
+// position.
+func Diagnostics(err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var diagnostics []Diagnostic
+		for _, err := range joined.Unwrap() {
+			diagnostics = append(diagnostics, Diagnostics(err)...)
+		}
+		return diagnostics
+	}
+	var located interface{ Diagnostic() Diagnostic }
+	if errors.As(err, &located) {
+		return []Diagnostic{located.Diagnostic()}
+	}
+	return []Diagnostic{{Message: err.Error()}}
+}
+
+// DiagnosticRenderer draws diagnostics the way rustc does: the message, the
+// file and line:column, the offending source line with the span underlined,
+// and any notes and help. Columns count characters, not bytes, unlike
+// Token.Column.
+type DiagnosticRenderer struct {
+	Filename string
+	Source   string
+	// Color enables ANSI colors, for output to a terminal.
+	Color bool
+}
+
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorError  = "\x1b[1;31m"
+	colorGutter = "\x1b[1;34m"
+)
+
+// Render draws every diagnostic in err.
+func (r DiagnosticRenderer) Render(w io.Writer, err error) {
+	for _, diagnostic := range Diagnostics(err) {
+		r.RenderDiagnostic(w, diagnostic)
+	}
+}
+
+// RenderDiagnostic draws a single diagnostic.
+func (r DiagnosticRenderer) RenderDiagnostic(w io.Writer, d Diagnostic) {
+	if d.Source != "" {
+		r.Source = d.Source
+		if d.Filename != "" {
+			r.Filename = d.Filename
+		}
+	}
+	fmt.Fprintf(w, "%s: %s\n", r.paint(colorError, "error"), r.paint(colorBold, d.Message))
+
+	line, column, text, ok := r.locate(d)
+	gutter := strings.Repeat(" ", len(fmt.Sprint(max(line, d.Line))))
+	switch {
+	case ok:
+		fmt.Fprintf(w, "%s%s %s\n", gutter, r.paint(colorGutter, "-->"), r.position(fmt.Sprintf("%d:%d", line, column)))
+		fmt.Fprintf(w, "%s %s\n", gutter, r.paint(colorGutter, "|"))
+		fmt.Fprintf(w, "%s %s %s\n", r.paint(colorGutter, fmt.Sprint(line)), r.paint(colorGutter, "|"), text)
+		fmt.Fprintf(w, "%s %s %s\n", gutter, r.paint(colorGutter, "|"), r.paint(colorError, underline(text, column, d.Span)))
+	case d.Line > 0:
+		fmt.Fprintf(w, "%s%s %s\n", gutter, r.paint(colorGutter, "-->"), r.position(fmt.Sprintf("%d", d.Line)))
+	}
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(w, "%s %s %s: %s\n", gutter, r.paint(colorGutter, "="), r.paint(colorBold, "note"), note)
+	}
+	if d.Help != "" {
+		fmt.Fprintf(w, "%s %s %s: %s\n", gutter, r.paint(colorGutter, "="), r.paint(colorBold, "help"), d.Help)
+	}
+}
+
+func (r DiagnosticRenderer) position(location string) string {
+	if r.Filename == "" {
+		return "line " + location
+	}
+	return r.Filename + ":" + location
+}
+
+func (r DiagnosticRenderer) paint(color, text string) string {
+	if !r.Color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// locate finds the line holding the start of the diagnostic's span and the
+// span's 1-based character column within it.
+func (r DiagnosticRenderer) locate(d Diagnostic) (line, column int, text string, ok bool) {
+	source, start := r.Source, d.Span.Start
+	if d.Span.IsZero() || start < 0 || d.Span.End > len(source) {
+		return 0, 0, "", false
+	}
+	if anchor := d.anchor; anchor.Lexeme != "" {
+		if anchor.End > len(source) || source[anchor.Start:anchor.End] != anchor.Lexeme {
+			return 0, 0, "", false
+		}
+	}
+	// The end of input is shown after the last line rather than on an
+	// empty line of its own.
+	if start == len(source) && start > 0 && source[start-1] == '\n' {
+		start--
+	}
+
+	lineStart := strings.LastIndexByte(source[:start], '\n') + 1
+	lineEnd := strings.IndexByte(source[start:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd += start
+	}
+	line = strings.Count(source[:lineStart], "\n") + 1
+	text = strings.TrimSuffix(source[lineStart:lineEnd], "\r")
+	return line, utf8.RuneCountInString(source[lineStart:start]) + 1, text, true
+}
+
+// underline puts carets under the part of the span on text, keeping tabs so
+// they line up. A span that is empty or starts at the end of the line gets
+// a single caret.
+func underline(text string, column int, span Span) string {
+	var builder strings.Builder
+	start, skipped := len(text), 0
+	for offset, char := range text {
+		if skipped == column-1 {
+			start = offset
+			break
+		}
+		if char == '\t' {
+			builder.WriteByte('\t')
+		} else {
+			builder.WriteByte(' ')
+		}
+		skipped++
+	}
+	end := min(start+span.End-span.Start, len(text))
+	builder.WriteString(strings.Repeat("^", max(utf8.RuneCountInString(text[start:end]), 1)))
+	return builder.String()
+}
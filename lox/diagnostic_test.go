@@ -0,0 +1,112 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// render runs source and returns its error drawn by a renderer for t.lox.
+func render(t *testing.T, source string) string {
+	t.Helper()
+	err := New(Options{Stdout: &bytes.Buffer{}}).Run(source)
+	if err == nil {
+		t.Fatalf("%q ran without an error", source)
+	}
+	var output bytes.Buffer
+	DiagnosticRenderer{Filename: "t.lox", Source: source}.Render(&output, err)
+	return output.String()
+}
+
+func TestRenderCaret(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{
+			"print 1 + nil;",
+			[]string{
+				"error: Operands must be two numbers or two strings.",
+				" --> t.lox:1:9",
+				"  |",
+				"1 | print 1 + nil;",
+				"  |         ^",
+			},
+		},
+		{
+			// Columns count characters, so the two-byte é counts once.
+			`var s = "é" + 1;`,
+			[]string{
+				"error: Operands must be two numbers or two strings.",
+				" --> t.lox:1:13",
+				"  |",
+				`1 | var s = "é" + 1;`,
+				"  |             ^",
+			},
+		},
+		{
+			// Tabs are kept under the source so the caret lines up.
+			"{\n\tprint -\"x\";\n}",
+			[]string{
+				"error: Operand must be a number.",
+				" --> t.lox:2:8",
+				"  |",
+				"2 | \tprint -\"x\";",
+				"  | \t      ^",
+			},
+		},
+		{
+			// A missing token at the end of input points just past the
+			// last one.
+			"print 1",
+			[]string{
+				"error: Expect ';' after value.",
+				" --> t.lox:1:8",
+				"  |",
+				"1 | print 1",
+				"  |        ^",
+				"  = help: add ';' after '1'",
+			},
+		},
+	}
+	for _, test := range tests {
+		if got, want := render(t, test.source), strings.Join(test.want, "\n")+"\n"; got != want {
+			t.Errorf("%q rendered as\n%s\nwant\n%s", test.source, got, want)
+		}
+	}
+}
+
+func TestRenderUnderlinesSpan(t *testing.T) {
+	source := "var total = price * quantité;\n"
+	start := strings.Index(source, "quantité")
+	diagnostic := Diagnostic{
+		Message: "Undefined variable 'quantité'.",
+		Span:    Span{Start: start, End: start + len("quantité")},
+		Line:    1,
+		Notes:   []string{"at main line 1"},
+	}
+	var output bytes.Buffer
+	DiagnosticRenderer{Source: source}.RenderDiagnostic(&output, diagnostic)
+	want := strings.Join([]string{
+		"error: Undefined variable 'quantité'.",
+		" --> line 1:21",
+		"  |",
+		"1 | var total = price * quantité;",
+		"  |                     ^^^^^^^^",
+		"  = note: at main line 1",
+	}, "\n") + "\n"
+	if got := output.String(); got != want {
+		t.Errorf("rendered as\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderWithoutSource(t *testing.T) {
+	diagnostic := Diagnostic{Message: "Stack overflow.", Span: Span{Start: 4, End: 5}, Line: 3}
+	var output bytes.Buffer
+	DiagnosticRenderer{Filename: "t.lox", Color: true}.RenderDiagnostic(&output, diagnostic)
+	want := "\x1b[1;31merror\x1b[0m: \x1b[1mStack overflow.\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m t.lox:3\n"
+	if got := output.String(); got != want {
+		t.Errorf("rendered as %q, want %q", got, want)
+	}
+}
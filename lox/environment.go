@@ -1,19 +1,26 @@
-package main
+package lox
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"sort"
 )
 
 type Environment struct {
 	values    map[string]interface{}
 	enclosing *Environment
+	trace     io.Writer
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
+	var trace io.Writer
+	if enclosing != nil {
+		trace = enclosing.trace
+	}
 	return &Environment{
 		values:    make(map[string]interface{}),
 		enclosing: enclosing,
+		trace:     trace,
 	}
 }
 
@@ -26,19 +33,25 @@ func (e *Environment) Define(name string, value interface{}) {
 
 func (e *Environment) Get(name string) (interface{}, error) {
 	if val, ok := e.values[name]; ok {
-		fmt.Fprintf(os.Stderr, "Get %s found in env %p\n", name, e)
+		if e.trace != nil {
+			fmt.Fprintf(e.trace, "Get %s found in env %p\n", name, e)
+		}
 		return val, nil
 	}
 	if e.enclosing != nil {
 		return e.enclosing.Get(name)
 	}
-	fmt.Fprintf(os.Stderr, "Get %s failed in env %p\n", name, e)
+	if e.trace != nil {
+		fmt.Fprintf(e.trace, "Get %s failed in env %p\n", name, e)
+	}
 	return nil, fmt.Errorf("Undefined variable '%s'.", name)
 }
 
 func (e *Environment) Assign(name Token, value interface{}) error {
 	if _, ok := e.values[name.Lexeme]; ok {
-		fmt.Fprintf(os.Stderr, "Assigning %s in env %p\n", name.Lexeme, e)
+		if e.trace != nil {
+			fmt.Fprintf(e.trace, "Assigning %s in env %p\n", name.Lexeme, e)
+		}
 		e.values[name.Lexeme] = value
 		return nil
 	}
@@ -66,3 +79,24 @@ func (e *Environment) ancestor(distance int) *Environment {
 func (e *Environment) AssignAt(distance int, name Token, value interface{}) {
 	e.ancestor(distance).values[name.Lexeme] = value
 }
+
+// Enclosing returns the surrounding scope, or nil for the globals.
+func (e *Environment) Enclosing() *Environment {
+	return e.enclosing
+}
+
+// Names returns the variables defined directly in this scope, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.values))
+	for name := range e.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a variable defined directly in this scope.
+func (e *Environment) Lookup(name string) (interface{}, bool) {
+	value, ok := e.values[name]
+	return value, ok
+}
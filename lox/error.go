@@ -0,0 +1,197 @@
+package lox
+
+import "fmt"
+
+type ScanError struct {
+	line    int
+	column  int
+	start   int
+	end     int
+	message string
+}
+
+func NewScanError(line int, message string) *ScanError {
+	return &ScanError{
+		line:    line,
+		message: message,
+	}
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("[line %d] Error: %s", e.line, e.message)
+}
+
+func (e *ScanError) Line() int {
+	return e.line
+}
+
+// Column returns the 1-based column where the offending text starts, or 0
+// when unknown.
+func (e *ScanError) Column() int {
+	return e.column
+}
+
+// Start and End return the byte offsets of the offending text.
+func (e *ScanError) Start() int {
+	return e.start
+}
+
+func (e *ScanError) End() int {
+	return e.end
+}
+
+func (e *ScanError) Message() string {
+	return e.message
+}
+
+func (e *ScanError) Diagnostic() Diagnostic {
+	diagnostic := Diagnostic{Message: e.message, Line: e.line}
+	if e.column > 0 {
+		diagnostic.Span = Span{Start: e.start, End: e.end}
+	}
+	return diagnostic
+}
+
+type RuntimeError struct {
+	token   Token
+	message string
+	cause   error
+	// span, when set, is shown instead of the token's, such as the whole
+	// call for an error raised by a native.
+	span Span
+	// stack is the call stack when the error was raised, innermost first.
+	stack []Frame
+	// source is the chunk holding token.
+	source *chunkSource
+}
+
+func NewRuntimeError(token Token, message string) *RuntimeError {
+	return &RuntimeError{
+		token:   token,
+		message: message,
+	}
+}
+
+func (e *RuntimeError) Error() string {
+	if e.token.Line == 0 {
+		return e.message
+	}
+	return fmt.Sprintf("%s\n[line %d]", e.message, e.token.Line)
+}
+
+// Unwrap returns ErrInterrupted or ErrDeadlineExceeded for errors caused by
+// cancellation.
+func (e *RuntimeError) Unwrap() error {
+	return e.cause
+}
+
+func (e *RuntimeError) Token() Token {
+	return e.token
+}
+
+func (e *RuntimeError) Message() string {
+	return e.message
+}
+
+func (e *RuntimeError) Diagnostic() Diagnostic {
+	if e.token.Line == 0 {
+		return Diagnostic{Message: e.message, Notes: shortTraceback(traceback(e.stack, 0, e.source.sourceName()))}
+	}
+	diagnostic := tokenDiagnostic(e.token, e.message)
+	if !e.span.IsZero() {
+		diagnostic.Span = e.span
+	}
+	if e.source != nil {
+		diagnostic.Filename, diagnostic.Source = e.source.name, e.source.text
+	}
+	diagnostic.Notes = shortTraceback(traceback(e.stack, e.token.Line, e.source.sourceName()))
+	return diagnostic
+}
+
+type ParseError struct {
+	token   Token
+	message string
+	help    string
+}
+
+func NewParseError(token Token, message string) *ParseError {
+	return &ParseError{
+		token:   token,
+		message: message,
+	}
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.token.Line, location(e.token), e.message)
+}
+
+func (e *ParseError) Token() Token {
+	return e.token
+}
+
+func (e *ParseError) Message() string {
+	return e.message
+}
+
+func (e *ParseError) Diagnostic() Diagnostic {
+	diagnostic := tokenDiagnostic(e.token, e.message)
+	diagnostic.Help = e.help
+	return diagnostic
+}
+
+type ResolveError struct {
+	token   Token
+	message string
+}
+
+func NewResolveError(token Token, message string) *ResolveError {
+	return &ResolveError{
+		token:   token,
+		message: message,
+	}
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.token.Line, location(e.token), e.message)
+}
+
+func (e *ResolveError) Token() Token {
+	return e.token
+}
+
+func (e *ResolveError) Message() string {
+	return e.message
+}
+
+func (e *ResolveError) Diagnostic() Diagnostic {
+	return tokenDiagnostic(e.token, e.message)
+}
+
+func location(token Token) string {
+	if token.Type == EOF {
+		return " at end"
+	}
+	return fmt.Sprintf(" at '%s'", token.Lexeme)
+}
+
+// attributeError records the running chunk as the source of a runtime
+// error raised in top-level code; errors raised in a function already name
+// the function's chunk. It is deferred before catchRuntimeError so that it
+// runs after it.
+func (i *Interpreter) attributeError(err *error) {
+	if runtimeErr, ok := (*err).(*RuntimeError); ok && runtimeErr.source == nil {
+		runtimeErr.source = i.source
+	}
+}
+
+// catchRuntimeError turns a RuntimeError panic into a returned error. It
+// must be deferred directly.
+func catchRuntimeError(err *error) {
+	if r := recover(); r != nil {
+		if runtimeErr, ok := r.(*RuntimeError); ok {
+			*err = runtimeErr
+			return
+		}
+		panic(r)
+	}
+}
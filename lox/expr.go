@@ -1,71 +1,89 @@
-package main
+package lox
 
 type Expr interface {
 	Accept(visitor ExprVisitor) interface{}
+	Span() Span
 }
 
 type Variable struct {
+	Spanned
 	Name Token
 }
 
 type Literal struct {
+	Spanned
 	Value interface{}
+	// Token is the literal's source token; it is zero for literals the
+	// parser synthesizes, such as the condition of `for (;;)`.
+	Token Token
 }
 
 type Binary struct {
+	Spanned
 	Left     Expr
 	Operator Token
 	Right    Expr
 }
 
 type Grouping struct {
+	Spanned
+	Paren      Token
 	Expression Expr
 }
 
 type Unary struct {
+	Spanned
 	Operator Token
 	Right    Expr
 }
 
 type Assign struct {
+	Spanned
 	Name  Token
 	Value Expr
 }
 
 type Logical struct {
+	Spanned
 	Left     Expr
 	Operator Token
 	Right    Expr
 }
 
 type Call struct {
+	Spanned
 	Callee    Expr
 	Paren     Token
 	Arguments []Expr
 }
 
 type FunctionExpr struct {
+	Spanned
 	Name   Token
 	Params []Token
 	Body   []Stmt
 }
 
 type Get struct {
+	Spanned
 	Object Expr
 	Name   Token
 }
 
 type Set struct {
+	Spanned
 	Object Expr
 	Name   Token
 	Value  Expr
 }
 
 type This struct {
+	Spanned
 	Keyword Token
 }
 
 type Super struct {
+	Spanned
 	Keyword Token
 	Method  Token
 }
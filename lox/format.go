@@ -0,0 +1,357 @@
+package lox
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	formatIndent = "  "
+	formatWidth  = 80
+)
+
+// Format returns source laid out canonically: one statement per line,
+// two-space indentation, braces on the line that opens them, single spaces
+// around binary operators and after commas, and calls longer than 80
+// columns split one argument per line. Comments and single blank lines
+// between statements are kept, and a comment after code stays after the
+// same tokens, ending the line there. Source that does not parse is returned with
+// the error unchanged.
+func Format(source string) (string, error) {
+	tokens, scanErrors := NewScanner(source).ScanTokens()
+	if len(scanErrors) > 0 {
+		return source, errors.Join(scanErrors...)
+	}
+	if _, err := NewParser(tokens).Parse(); err != nil {
+		return source, err
+	}
+
+	f := &formatter{tokens: tokens}
+	f.format()
+
+	var builder strings.Builder
+	for _, line := range f.lines {
+		for _, wrapped := range wrapLine(line) {
+			builder.WriteString(wrapped.render())
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String(), nil
+}
+
+// formatLine is one output line before wrapping. A line with no tokens and
+// no comment is a blank line.
+type formatLine struct {
+	indent  int
+	tokens  []Token
+	comment string
+}
+
+type formatter struct {
+	tokens  []Token
+	lines   []*formatLine
+	current *formatLine
+	// last is the line holding the most recent token, where a comment
+	// that follows on the same source line goes.
+	last   *formatLine
+	indent int
+	depth  int
+	// continued is set when a trailing comment breaks a statement across
+	// lines, so the rest of the statement is indented one more level.
+	continued bool
+}
+
+func (f *formatter) format() {
+	f.current = &formatLine{}
+	for index := 0; index < len(f.tokens); index++ {
+		token := f.tokens[index]
+		f.trivia(token)
+		if token.Type == EOF {
+			break
+		}
+
+		switch token.Type {
+		case LEFT_BRACE:
+			f.continued = false
+			f.add(token)
+			if next := f.tokens[index+1]; next.Type == RIGHT_BRACE && !hasComment(next) {
+				index++
+				f.add(next)
+				f.afterBrace(index)
+				continue
+			}
+			f.indent++
+			f.endLine()
+		case RIGHT_BRACE:
+			f.continued = false
+			f.endLine()
+			f.indent--
+			f.current.indent = f.indent
+			f.add(token)
+			f.afterBrace(index)
+		case SEMICOLON:
+			f.add(token)
+			if f.depth == 0 {
+				f.continued = false
+				f.endLine()
+			}
+		case LEFT_PAREN:
+			f.depth++
+			f.add(token)
+		case RIGHT_PAREN:
+			f.depth--
+			f.add(token)
+		default:
+			f.add(token)
+		}
+	}
+	f.endLine()
+}
+
+// afterBrace ends the line after a closing brace unless the statement goes
+// on, as in `} else {` or `return fun f() {};`.
+func (f *formatter) afterBrace(index int) {
+	switch f.tokens[index+1].Type {
+	case ELSE, SEMICOLON, COMMA, RIGHT_PAREN:
+		return
+	}
+	f.endLine()
+}
+
+// trivia places the comments before token and keeps one blank line where
+// the source had any between statements.
+func (f *formatter) trivia(token Token) {
+	newlines := 0
+	for _, trivia := range token.Leading {
+		switch trivia.Kind {
+		case NewlineTrivia:
+			newlines++
+		case CommentTrivia:
+			if newlines == 0 && f.last != nil {
+				// A trailing comment stays on the line it was written on,
+				// so what follows it, such as `else` after `} // done`,
+				// starts a new line.
+				f.last.comment = trivia.Text
+				if f.last == f.current {
+					last := f.current.tokens[len(f.current.tokens)-1]
+					f.continued = last.Type != RIGHT_BRACE
+					f.endLine()
+				}
+				continue
+			}
+			f.endLine()
+			f.blankLine(newlines)
+			f.current.comment = trivia.Text
+			f.endLine()
+			newlines = 0
+		}
+	}
+	if len(f.current.tokens) == 0 && token.Type != RIGHT_BRACE && token.Type != EOF {
+		f.blankLine(newlines)
+	}
+}
+
+func (f *formatter) blankLine(newlines int) {
+	if newlines < 2 || len(f.lines) == 0 {
+		return
+	}
+	previous := f.lines[len(f.lines)-1]
+	if len(previous.tokens) == 0 && previous.comment == "" {
+		return
+	}
+	if count := len(previous.tokens); count > 0 && previous.tokens[count-1].Type == LEFT_BRACE {
+		return
+	}
+	f.lines = append(f.lines, &formatLine{})
+}
+
+// lineIndent is the indentation for a new line; lines that continue a
+// parenthesized list or a statement broken by a comment get one extra
+// level.
+func (f *formatter) lineIndent() int {
+	if f.depth > 0 || f.continued {
+		return f.indent + 1
+	}
+	return f.indent
+}
+
+func (f *formatter) add(token Token) {
+	if len(f.current.tokens) == 0 && f.current.comment == "" {
+		f.current.indent = f.lineIndent()
+	}
+	f.current.tokens = append(f.current.tokens, token)
+	f.last = f.current
+}
+
+func (f *formatter) endLine() {
+	if len(f.current.tokens) > 0 || f.current.comment != "" {
+		if len(f.current.tokens) == 0 {
+			f.current.indent = f.lineIndent()
+		}
+		f.lines = append(f.lines, f.current)
+	}
+	f.current = &formatLine{indent: f.lineIndent()}
+}
+
+func hasComment(token Token) bool {
+	for _, trivia := range token.Leading {
+		if trivia.Kind == CommentTrivia {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *formatLine) render() string {
+	if len(l.tokens) == 0 && l.comment == "" {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString(strings.Repeat(formatIndent, l.indent))
+	for index, token := range l.tokens {
+		if index > 0 && spaceBetween(l.tokens, index) {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(token.Lexeme)
+	}
+	if l.comment != "" {
+		if len(l.tokens) > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(l.comment)
+	}
+	return builder.String()
+}
+
+// spaceBetween reports whether tokens[index] is separated from the token
+// before it.
+func spaceBetween(tokens []Token, index int) bool {
+	previous, token := tokens[index-1], tokens[index]
+
+	switch token.Type {
+	case RIGHT_PAREN, COMMA, SEMICOLON, DOT:
+		return false
+	case RIGHT_BRACE:
+		return previous.Type != LEFT_BRACE
+	}
+
+	switch previous.Type {
+	case LEFT_PAREN, DOT, BANG:
+		return false
+	case MINUS:
+		// A minus that does not follow an operand is unary.
+		if index < 2 || !endsValue(tokens[index-2]) {
+			return false
+		}
+	}
+	return token.Type != LEFT_PAREN || !isCallParen(tokens, index)
+}
+
+// isCallParen reports whether the parenthesis at index opens an argument or
+// parameter list rather than a grouping or a statement's condition.
+func isCallParen(tokens []Token, index int) bool {
+	if index == 0 {
+		return false
+	}
+	switch tokens[index-1].Type {
+	case IDENTIFIER, RIGHT_PAREN:
+		return true
+	}
+	return false
+}
+
+// endsValue reports whether token can end an operand, which makes a
+// following minus binary rather than unary.
+func endsValue(token Token) bool {
+	switch token.Type {
+	case IDENTIFIER, NUMBER, STRING, RIGHT_PAREN, TRUE, FALSE, NIL, THIS:
+		return true
+	}
+	return false
+}
+
+// wrapLine splits a line wider than formatWidth at the outermost argument
+// list that runs past the limit, putting each argument on its own line,
+// and wraps the pieces again if they are still too wide.
+func wrapLine(line *formatLine) []*formatLine {
+	if len(line.render()) <= formatWidth {
+		return []*formatLine{line}
+	}
+
+	if open, closing := overflowingCall(line); open >= 0 {
+		pieces := []*formatLine{{indent: line.indent, tokens: line.tokens[:open+1]}}
+		argumentStart, depth := open+1, 0
+		for index := open + 1; index < closing; index++ {
+			switch line.tokens[index].Type {
+			case LEFT_PAREN:
+				depth++
+			case RIGHT_PAREN:
+				depth--
+			case COMMA:
+				if depth == 0 {
+					pieces = append(pieces, &formatLine{indent: line.indent + 1, tokens: line.tokens[argumentStart : index+1]})
+					argumentStart = index + 1
+				}
+			}
+		}
+		pieces = append(pieces,
+			&formatLine{indent: line.indent + 1, tokens: line.tokens[argumentStart:closing]},
+			&formatLine{indent: line.indent, tokens: line.tokens[closing:], comment: line.comment})
+
+		var wrapped []*formatLine
+		for _, piece := range pieces {
+			wrapped = append(wrapped, wrapLine(piece)...)
+		}
+		return wrapped
+	}
+	return []*formatLine{line}
+}
+
+// overflowingCall returns the parentheses of the argument list to split:
+// the least nested one that closes past formatWidth or, when the overflow
+// comes after every list, such as from a trailing `;`, the one that closes
+// last. It returns -1 when the line has no non-empty argument list.
+func overflowingCall(line *formatLine) (open, closing int) {
+	open, closing = -1, -1
+	overflows, outermost, depth := false, 0, 0
+	for index, token := range line.tokens {
+		switch token.Type {
+		case RIGHT_PAREN:
+			depth--
+		case LEFT_PAREN:
+			depth++
+			if !isCallParen(line.tokens, index) {
+				continue
+			}
+			end := matchingParen(line.tokens, index)
+			if end < 0 || end == index+1 {
+				continue
+			}
+			prefix := formatLine{indent: line.indent, tokens: line.tokens[:end+1]}
+			if len(prefix.render()) > formatWidth {
+				if !overflows || depth < outermost {
+					open, closing, outermost, overflows = index, end, depth, true
+				}
+			} else if !overflows && end > closing {
+				open, closing = index, end
+			}
+		}
+	}
+	return open, closing
+}
+
+func matchingParen(tokens []Token, open int) int {
+	depth := 0
+	for index := open; index < len(tokens); index++ {
+		switch tokens[index].Type {
+		case LEFT_PAREN:
+			depth++
+		case RIGHT_PAREN:
+			depth--
+			if depth == 0 {
+				return index
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,70 @@
+package lox
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestFormatGolden formats each testdata/format/*.lox file and compares the
+// result with the .golden file beside it. Run with -update to rewrite them.
+func TestFormatGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "format", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no formatter test files")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, err := Format(string(source))
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+
+			golden := strings.TrimSuffix(input, ".lox") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(formatted), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != string(want) {
+				t.Errorf("Format(%s) =\n%s\nwant:\n%s", input, formatted, want)
+			}
+
+			again, err := Format(formatted)
+			if err != nil {
+				t.Fatalf("Format of formatted output: %v", err)
+			}
+			if again != formatted {
+				t.Errorf("Format is not idempotent on %s; second pass gave:\n%s", input, again)
+			}
+		})
+	}
+}
+
+func TestFormatKeepsSourceThatDoesNotParse(t *testing.T) {
+	for _, source := range []string{"var = 1;", "print \"unterminated;"} {
+		formatted, err := Format(source)
+		if err == nil {
+			t.Errorf("Format(%q) succeeded, want an error", source)
+		}
+		if formatted != source {
+			t.Errorf("Format(%q) = %q, want the source unchanged", source, formatted)
+		}
+	}
+}
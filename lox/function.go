@@ -1,4 +1,4 @@
-package main
+package lox
 
 import (
 	"fmt"
@@ -8,16 +8,20 @@ type LoxFunction struct {
 	declaration   *Function
 	closure       *Environment
 	isInitializer bool
+	locals        Locals
+	// source is the chunk the function was declared in.
+	source *chunkSource
 }
 
 type Function struct {
+	Spanned
 	Name   Token
 	Params []Token
 	Body   []Stmt
 }
 
-func NewLoxFunction(declaration *Function, closure *Environment, isInitializer bool) *LoxFunction {
-	return &LoxFunction{declaration: declaration, closure: closure, isInitializer: isInitializer}
+func NewLoxFunction(declaration *Function, closure *Environment, isInitializer bool, locals Locals) *LoxFunction {
+	return &LoxFunction{declaration: declaration, closure: closure, isInitializer: isInitializer, locals: locals}
 }
 
 func (f *LoxFunction) Call(interpreter *Interpreter, arguments []interface{}) (result interface{}) {
@@ -26,13 +30,23 @@ func (f *LoxFunction) Call(interpreter *Interpreter, arguments []interface{}) (r
 		environment.Define(f.declaration.Params[i].Lexeme, arguments[i])
 	}
 
+	// The body was resolved along with the chunk that declared it.
+	previousLocals, previousSource := interpreter.locals, interpreter.source
+	interpreter.locals, interpreter.source = f.locals, f.source
+	defer func() {
+		interpreter.locals, interpreter.source = previousLocals, previousSource
+	}()
+
 	defer func() {
 		if r := recover(); r != nil {
 			if ret, ok := r.(*ReturnValue); ok {
 				result = ret.Value
-			} else {
-				panic(r)
+				return
+			}
+			if err, ok := r.(*RuntimeError); ok && err.source == nil {
+				err.source = f.source
 			}
+			panic(r)
 		}
 	}()
 
@@ -59,5 +73,7 @@ func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
 		declaration:   f.declaration,
 		closure:       env,
 		isInitializer: f.isInitializer,
+		locals:        f.locals,
+		source:        f.source,
 	}
 }
@@ -0,0 +1,225 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HostObject is implemented by Go values that want full control over how
+// scripts read and write their properties. Methods can be exposed by
+// returning a Go function from GetProperty.
+type HostObject interface {
+	GetProperty(name string) (interface{}, error)
+	SetProperty(name string, value interface{}) error
+}
+
+// HostInstance exposes a Go struct, pointer, map or HostObject to scripts.
+// Properties resolve to methods first, then struct fields or map keys. A
+// lowercase Lox name also matches the exported Go name, so `p.area()` calls
+// Area and `p.x` reads X; a `lox:"name"` field tag overrides the name.
+type HostInstance struct {
+	value reflect.Value
+	// readOnly marks a copy, such as a struct read out of a map, that
+	// scripts can't change in place.
+	readOnly bool
+}
+
+// NewHostInstance wraps value so scripts can access it by reference. Pass a
+// pointer to let scripts modify struct fields.
+func NewHostInstance(value interface{}) (*HostInstance, error) {
+	v := reflect.ValueOf(value)
+	if _, ok := value.(HostObject); ok {
+		return &HostInstance{value: v}, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot expose a nil %s", v.Type())
+		}
+		if v.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("cannot expose %s: expected a pointer to a struct", v.Type())
+		}
+	case reflect.Struct:
+		pointer := reflect.New(v.Type())
+		pointer.Elem().Set(v)
+		v = pointer
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot expose %s: keys must be strings", v.Type())
+		}
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot expose a nil %s", v.Type())
+		}
+	default:
+		return nil, fmt.Errorf("cannot expose %T", value)
+	}
+	return &HostInstance{value: v}, nil
+}
+
+// Expose defines a global that gives scripts live access to value. See
+// HostInstance for how properties are resolved.
+func (i *Interpreter) Expose(name string, value interface{}) error {
+	host, err := NewHostInstance(value)
+	if err != nil {
+		return err
+	}
+	i.globals.Define(name, host)
+	return nil
+}
+
+// Interface returns the wrapped Go value.
+func (h *HostInstance) Interface() interface{} {
+	return h.value.Interface()
+}
+
+func (h *HostInstance) String() string {
+	if stringer, ok := h.value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	t := h.value.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return fmt.Sprintf("%s instance", t.Name())
+}
+
+func (h *HostInstance) Get(name Token) interface{} {
+	if object, ok := h.value.Interface().(HostObject); ok {
+		value, err := object.GetProperty(name.Lexeme)
+		if err != nil {
+			panic(NewRuntimeError(name, err.Error()))
+		}
+		return h.toLox(name, value)
+	}
+
+	if method := h.method(name.Lexeme); method.IsValid() {
+		native, err := wrapFunc(name.Lexeme, method)
+		if err != nil {
+			panic(NewRuntimeError(name, err.Error()))
+		}
+		return native
+	}
+
+	if h.value.Kind() == reflect.Map {
+		if value := h.value.MapIndex(reflect.ValueOf(name.Lexeme).Convert(h.value.Type().Key())); value.IsValid() {
+			return h.property(name, value)
+		}
+	} else if field := h.field(name.Lexeme); field.IsValid() {
+		return h.property(name, field)
+	}
+
+	panic(&RuntimeError{
+		token:   name,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+	})
+}
+
+func (h *HostInstance) Set(name Token, value interface{}) {
+	if h.readOnly {
+		panic(NewRuntimeError(name, fmt.Sprintf("Can't set property '%s' on a copy of a map entry.", name.Lexeme)))
+	}
+	if object, ok := h.value.Interface().(HostObject); ok {
+		if err := object.SetProperty(name.Lexeme, value); err != nil {
+			panic(NewRuntimeError(name, err.Error()))
+		}
+		return
+	}
+
+	if h.value.Kind() == reflect.Map {
+		converted, err := FromLox(value, h.value.Type().Elem())
+		if err != nil {
+			panic(NewRuntimeError(name, fmt.Sprintf("Can't set property '%s': %s", name.Lexeme, err)))
+		}
+		h.value.SetMapIndex(reflect.ValueOf(name.Lexeme).Convert(h.value.Type().Key()), converted)
+		return
+	}
+
+	field := h.field(name.Lexeme)
+	if !field.IsValid() {
+		panic(&RuntimeError{
+			token:   name,
+			message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+		})
+	}
+	if !field.CanSet() {
+		panic(NewRuntimeError(name, fmt.Sprintf("Can't set property '%s'.", name.Lexeme)))
+	}
+	converted, err := FromLox(value, field.Type())
+	if err != nil {
+		panic(NewRuntimeError(name, fmt.Sprintf("Can't set property '%s': %s", name.Lexeme, err)))
+	}
+	field.Set(converted)
+}
+
+func (h *HostInstance) method(name string) reflect.Value {
+	for _, candidate := range goNames(name) {
+		if method := h.value.MethodByName(candidate); method.IsValid() {
+			return method
+		}
+	}
+	return reflect.Value{}
+}
+
+func (h *HostInstance) field(name string) reflect.Value {
+	structValue := h.value
+	if structValue.Kind() == reflect.Pointer {
+		structValue = structValue.Elem()
+	}
+	if structValue.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	structType := structValue.Type()
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if tag, ok := field.Tag.Lookup("lox"); ok && tag == name && field.IsExported() {
+			return structValue.Field(index)
+		}
+	}
+	for _, candidate := range goNames(name) {
+		if field, ok := structType.FieldByName(candidate); ok && field.IsExported() {
+			if _, tagged := field.Tag.Lookup("lox"); !tagged {
+				return structValue.FieldByIndex(field.Index)
+			}
+		}
+	}
+	return reflect.Value{}
+}
+
+// property converts a field or map entry for scripts. A struct field is
+// wrapped by reference so that scripts change it in place; a struct in a
+// map can't be addressed, so it is handed out as a read-only copy.
+func (h *HostInstance) property(name Token, value reflect.Value) interface{} {
+	if value.Kind() != reflect.Struct {
+		return h.toLox(name, value.Interface())
+	}
+	if value.CanAddr() {
+		return &HostInstance{value: value.Addr()}
+	}
+	host, err := NewHostInstance(value.Interface())
+	if err != nil {
+		panic(NewRuntimeError(name, err.Error()))
+	}
+	host.readOnly = true
+	return host
+}
+
+func (h *HostInstance) toLox(name Token, value interface{}) interface{} {
+	converted, err := ToLox(value)
+	if err != nil {
+		panic(NewRuntimeError(name, err.Error()))
+	}
+	return converted
+}
+
+// goNames returns the Go identifiers a Lox property name may refer to.
+func goNames(name string) []string {
+	first, size := utf8.DecodeRuneInString(name)
+	if unicode.IsUpper(first) {
+		return []string{name}
+	}
+	return []string{strings.ToUpper(string(first)) + name[size:], name}
+}
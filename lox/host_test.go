@@ -0,0 +1,122 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+type hostInner struct {
+	X int
+}
+
+func (i *hostInner) Double() int {
+	return i.X * 2
+}
+
+type hostOuter struct {
+	Name   string
+	Count  int
+	Inner  hostInner
+	secret int
+	Tagged int `lox:"tag"`
+	hidden int `lox:"hidden"`
+}
+
+func (o *hostOuter) Greet(greeting string) string {
+	return fmt.Sprintf("%s, %s", greeting, o.Name)
+}
+
+// runHost runs source with value exposed as the global "o" and returns what
+// it printed.
+func runHost(t *testing.T, source string, value interface{}) (string, error) {
+	t.Helper()
+	var output bytes.Buffer
+	interpreter := New(Options{Stdout: &output})
+	if err := interpreter.Expose("o", value); err != nil {
+		t.Fatalf("Expose: %v", err)
+	}
+	err := interpreter.Run(source)
+	return output.String(), err
+}
+
+func TestHostScalarFields(t *testing.T) {
+	outer := &hostOuter{Name: "ada", Count: 1}
+	output, err := runHost(t, `print o.name; o.count = o.count + 1; o.tag = 7; print o.count;`, outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ada\n2\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+	if outer.Count != 2 || outer.Tagged != 7 {
+		t.Errorf("outer = %+v, want Count 2 and Tagged 7", *outer)
+	}
+
+	_, err = runHost(t, `o.count = "many";`, outer)
+	if got, want := runtimeMessage(t, err), "Can't set property 'count': expected a number but got a string"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestHostNestedStructField(t *testing.T) {
+	outer := &hostOuter{Inner: hostInner{X: 1}}
+	output, err := runHost(t, `var inner = o.inner; inner.x = 5; print o.inner.x; o.inner.x = o.inner.x + 1; print o.inner.double();`, outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5\n12\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+	if outer.Inner.X != 6 {
+		t.Errorf("Inner.X = %d, want 6", outer.Inner.X)
+	}
+}
+
+func TestHostMethodCall(t *testing.T) {
+	output, err := runHost(t, `print o.greet("hello"); var greet = o.greet; print greet("hi");`, &hostOuter{Name: "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hello, ada\nhi, ada\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	_, err = runHost(t, `o.greet();`, &hostOuter{})
+	if got, want := runtimeMessage(t, err), "Expected 1 arguments but got 0."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestHostUnexportedFields(t *testing.T) {
+	for source, want := range map[string]string{
+		`print o.secret;`: "Undefined property 'secret'.",
+		`o.secret = 1;`:   "Undefined property 'secret'.",
+		`print o.hidden;`: "Undefined property 'hidden'.",
+		`o.hidden = 1;`:   "Undefined property 'hidden'.",
+	} {
+		_, err := runHost(t, source, &hostOuter{secret: 1, hidden: 2})
+		if got := runtimeMessage(t, err); got != want {
+			t.Errorf("%s: error = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestHostMapEntryIsReadOnly(t *testing.T) {
+	entries := map[string]hostInner{"a": {X: 1}}
+	output, err := runHost(t, `print o.a.x; print o.a.double();`, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n2\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	_, err = runHost(t, `o.a.x = 5;`, entries)
+	if got, want := runtimeMessage(t, err), "Can't set property 'x' on a copy of a map entry."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if entries["a"].X != 1 {
+		t.Errorf("entry changed to %d", entries["a"].X)
+	}
+}
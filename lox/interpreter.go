@@ -1,36 +1,85 @@
-package main
+package lox
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
 
 type Interpreter struct {
-	environment *Environment
-	globals     *Environment
-	locals      map[Expr]int
-}
-
-func NewInterpreter() *Interpreter {
+	environment  *Environment
+	globals      *Environment
+	locals       Locals
+	stdout       io.Writer
+	trace        io.Writer
+	stdin        *bufio.Reader
+	ctx          context.Context
+	timeout      time.Duration
+	limits       Limits
+	entries      int
+	frames       []Frame
+	source       *chunkSource
+	steps        int64
+	allocations  int64
+	audit        func(AuditEvent) error
+	fsRoot       string
+	fsRootHandle *os.Root
+}
+
+func NewInterpreter(opts Options) *Interpreter {
 	globals := NewEnvironment(nil)
 	i := &Interpreter{
 		environment: globals,
 		globals:     globals,
-		locals:      make(map[Expr]int),
+		locals:      make(Locals),
+		stdout:      os.Stdout,
+		stdin:       bufio.NewReader(os.Stdin),
+		ctx:         context.Background(),
+		timeout:     opts.Timeout,
+		limits:      opts.Limits,
+	}
+	if opts.Stdout != nil {
+		i.stdout = opts.Stdout
+	}
+	if opts.Stdin != nil {
+		i.stdin = bufio.NewReader(opts.Stdin)
 	}
+	i.trace = opts.Trace
+	globals.trace = i.trace
 
-	i.globals.Define("clock", &NativeFunction{
-		name:  "clock",
-		arity: 0,
-		function: func(arguments []interface{}) interface{} {
-			return float64(time.Now().Unix())
-		},
-	})
+	i.installCapabilities(opts)
 	return i
 }
 
+// Environment returns the innermost scope currently executing. Between runs
+// it is the globals.
+func (i *Interpreter) Environment() *Environment {
+	return i.environment
+}
+
+// Globals returns the global scope.
+func (i *Interpreter) Globals() *Environment {
+	return i.globals
+}
+
+// SetOutput redirects program output and debug traces. A nil trace turns
+// tracing off.
+func (i *Interpreter) SetOutput(stdout, trace io.Writer) {
+	i.stdout = stdout
+	i.trace = trace
+	i.globals.trace = trace
+}
+
+// SetInput replaces the reader consumed by readLine.
+func (i *Interpreter) SetInput(stdin io.Reader) {
+	i.stdin = bufio.NewReader(stdin)
+}
+
 func (i *Interpreter) Evaluate(expr Expr) interface{} {
+	i.steps++
 	return expr.Accept(i)
 }
 
@@ -43,7 +92,9 @@ func (i *Interpreter) VisitVarStmt(stmt *Var) interface{} {
 	if stmt.Initializer != nil {
 		value = i.Evaluate(stmt.Initializer)
 	}
-	fmt.Fprintf(os.Stderr, "Defining %s in environment %p (parent %p)\n", stmt.Name.Lexeme, i.environment, i.environment.enclosing)
+	if i.trace != nil {
+		fmt.Fprintf(i.trace, "Defining %s in environment %p (parent %p)\n", stmt.Name.Lexeme, i.environment, i.environment.enclosing)
+	}
 	i.environment.Define(stmt.Name.Lexeme, value)
 	return nil
 }
@@ -103,9 +154,9 @@ func (i *Interpreter) VisitBinaryExpr(expr *Binary) interface{} {
 		}
 		return left.(float64) / right.(float64)
 	case EQUAL_EQUAL:
-		return i.isEqual(left, right)
+		return isEqual(left, right)
 	case BANG_EQUAL:
-		return !i.isEqual(left, right)
+		return !isEqual(left, right)
 	case GREATER:
 		if _, ok := left.(float64); !ok {
 			panic(&RuntimeError{
@@ -165,6 +216,7 @@ func (i *Interpreter) VisitBinaryExpr(expr *Binary) interface{} {
 	case PLUS:
 		if lStr, lOk := left.(string); lOk {
 			if rStr, rOk := right.(string); rOk {
+				i.checkStringLength(expr.Operator, len(lStr)+len(rStr))
 				return lStr + rStr
 			}
 		}
@@ -195,28 +247,46 @@ func (i *Interpreter) VisitBinaryExpr(expr *Binary) interface{} {
 	return nil
 }
 
+// AddLocals merges resolution results into the table Interpret uses, for
+// callers that parse and resolve statements themselves.
+func (i *Interpreter) AddLocals(locals Locals) {
+	for expr, distance := range locals {
+		i.locals[expr] = distance
+	}
+}
+
+// Interpret executes statements and returns the first runtime error.
 func (i *Interpreter) Interpret(statements []Stmt) error {
-	defer func() {
-		if r := recover(); r != nil {
-			if err, ok := r.(*RuntimeError); ok {
-				fmt.Fprintf(os.Stderr, "%s\n [line %d]\n", err.message, err.token.Line)
-				os.Exit(70)
-			}
-		}
-	}()
+	return i.InterpretContext(i.ctx, statements)
+}
+
+// InterpretContext is like Interpret but stops with a runtime error wrapping
+// ErrInterrupted or ErrDeadlineExceeded once ctx is done.
+func (i *Interpreter) InterpretContext(ctx context.Context, statements []Stmt) error {
+	_, err := i.interpret(ctx, statements)
+	return err
+}
+
+// interpret executes statements and returns the result of the last one,
+// which for an expression statement is the expression's value.
+func (i *Interpreter) interpret(ctx context.Context, statements []Stmt) (last interface{}, err error) {
+	defer i.withContext(ctx)()
+	defer i.attributeError(&err)
+	defer catchRuntimeError(&err)
 
 	for _, statement := range statements {
-		i.Execute(statement)
+		last = i.Execute(statement)
 	}
 
-	return nil
+	return last, nil
 }
 
 func (i *Interpreter) Execute(stmt Stmt) interface{} {
+	i.steps++
 	return stmt.Accept(i)
 }
 
-func (i *Interpreter) isEqual(left, right interface{}) bool {
+func isEqual(left, right interface{}) bool {
 	if left == nil && right == nil {
 		return true
 	}
@@ -254,7 +324,8 @@ func (i *Interpreter) isTruthy(object interface{}) bool {
 	return true
 }
 
-func (i *Interpreter) stringify(obj interface{}) string {
+// Stringify formats a Lox value the way the print statement does.
+func Stringify(obj interface{}) string {
 	if obj == nil {
 		return "nil"
 	}
@@ -270,13 +341,12 @@ func (i *Interpreter) stringify(obj interface{}) string {
 }
 
 func (i *Interpreter) VisitExpressionStmt(stmt *Expression) interface{} {
-	i.Evaluate(stmt.Expression)
-	return nil
+	return i.Evaluate(stmt.Expression)
 }
 
 func (i *Interpreter) VisitPrintStmt(stmt *Print) interface{} {
 	value := i.Evaluate(stmt.Expression)
-	fmt.Println(i.stringify(value))
+	fmt.Fprintln(i.stdout, Stringify(value))
 	return value
 }
 
@@ -304,7 +374,9 @@ func (i *Interpreter) VisitBlockStmt(stmt *Block) interface{} {
 }
 
 func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment) interface{} {
-	fmt.Fprintf(os.Stderr, "Entering block. Env: %p, Parent: %p, Keys: %v\n", i.environment, i.environment.enclosing, i.environment.values)
+	if i.trace != nil {
+		fmt.Fprintf(i.trace, "Entering block. Env: %p, Parent: %p, Keys: %v\n", i.environment, i.environment.enclosing, i.environment.values)
+	}
 	previous := i.environment
 	i.environment = environment
 
@@ -315,7 +387,9 @@ func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment)
 	for _, statement := range statements {
 		i.Execute(statement)
 	}
-	fmt.Fprintf(os.Stderr, "Leaving block. Env: %p\n", i.environment)
+	if i.trace != nil {
+		fmt.Fprintf(i.trace, "Leaving block. Env: %p\n", i.environment)
+	}
 
 	return nil
 }
@@ -345,6 +419,8 @@ func (i *Interpreter) VisitLogicalExpr(expr *Logical) interface{} {
 
 func (i *Interpreter) VisitWhileStmt(stmt *While) interface{} {
 	for i.isTruthy(i.Evaluate(stmt.Condition)) {
+		i.checkInterrupt(stmt.Keyword)
+		i.checkSteps(stmt.Keyword)
 		result := i.Execute(stmt.Body)
 		if _, ok := result.(ReturnValue); ok {
 			return result
@@ -366,15 +442,38 @@ func (i *Interpreter) VisitCallExpr(expr *Call) interface{} {
 		panic(&RuntimeError{
 			token:   expr.Paren,
 			message: "Can only call functions and classes.",
+			span:    expr.Callee.Span(),
 		})
 	}
 
-	if len(arguments) != function.Arity() {
-		panic(&RuntimeError{
-			token:   expr.Paren,
-			message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)),
-		})
-	}
+	checkArity(function, expr.Paren, len(arguments))
+	return i.call(function, expr.Paren, expr.Span(), arguments)
+}
+
+// call invokes function on a new stack frame. Runtime errors raised by
+// natives carry no token, so they are attributed to the call site, and the
+// innermost call an error passes through records the stack.
+func (i *Interpreter) call(function LoxCallable, paren Token, site Span, arguments []interface{}) interface{} {
+	i.checkInterrupt(paren)
+	i.checkSteps(paren)
+	frame := newFrame(function, paren.Line)
+	frame.Source = i.source.sourceName()
+	i.enterCall(paren, frame)
+	defer i.leaveCall()
+	defer func() {
+		if r := recover(); r != nil {
+			if err, ok := r.(*RuntimeError); ok {
+				if err.token.Type == "" {
+					err.token = paren
+					err.span = site
+				}
+				if err.stack == nil {
+					err.stack = i.Stack()
+				}
+			}
+			panic(r)
+		}
+	}()
 	return function.Call(i, arguments)
 }
 
@@ -383,6 +482,8 @@ func (i *Interpreter) VisitFunctionStmt(stmt *Function) interface{} {
 		declaration:   stmt,
 		closure:       i.environment,
 		isInitializer: false,
+		locals:        i.locals,
+		source:        i.source,
 	}
 	i.environment.Define(stmt.Name.Lexeme, function)
 	return nil
@@ -396,12 +497,17 @@ func (i *Interpreter) VisitReturnStmt(stmt *ReturnStmt) interface{} {
 	panic(&ReturnValue{Value: value})
 }
 
-func (i *Interpreter) resolve(expr Expr, depth int) {
-	i.locals[expr] = depth
+// newResolver returns a resolver that traces wherever the interpreter does.
+func (i *Interpreter) newResolver() *Resolver {
+	resolver := NewResolver()
+	resolver.trace = i.trace
+	return resolver
 }
 
 func (i *Interpreter) lookupVariable(name Token, expr Expr) interface{} {
-	fmt.Fprintf(os.Stderr, "Looking up %s in env %p (parent %p), keys: %v\n", name.Lexeme, i.environment, i.environment.enclosing, i.environment.values)
+	if i.trace != nil {
+		fmt.Fprintf(i.trace, "Looking up %s in env %p (parent %p), keys: %v\n", name.Lexeme, i.environment, i.environment.enclosing, i.environment.values)
+	}
 
 	if distance, ok := i.locals[expr]; ok {
 		return i.environment.GetAt(distance, name.Lexeme)
@@ -419,15 +525,20 @@ func (i *Interpreter) VisitResolverStmt(stmt *Resolver) interface{} {
 
 func (i *Interpreter) VisitFunctionExpr(expr *FunctionExpr) interface{} {
 	function := &Function{
-		Name:   expr.Name,
-		Params: expr.Params,
-		Body:   expr.Body,
+		Spanned: expr.Spanned,
+		Name:    expr.Name,
+		Params:  expr.Params,
+		Body:    expr.Body,
 	}
-	return NewLoxFunction(function, i.environment, false)
+	closure := NewLoxFunction(function, i.environment, false, i.locals)
+	closure.source = i.source
+	return closure
 }
 
 func (i *Interpreter) VisitClassStmt(stmt *Class) interface{} {
-	fmt.Fprintf(os.Stderr, "Defining class %s in env %p (parent %p), keys: %v\n", stmt.Name.Lexeme, i.environment, i.environment.enclosing, i.environment.values)
+	if i.trace != nil {
+		fmt.Fprintf(i.trace, "Defining class %s in env %p (parent %p), keys: %v\n", stmt.Name.Lexeme, i.environment, i.environment.enclosing, i.environment.values)
+	}
 
 	var superclass *LoxClass = nil
 	if stmt.Superclass != nil {
@@ -435,7 +546,10 @@ func (i *Interpreter) VisitClassStmt(stmt *Class) interface{} {
 		var ok bool
 		superclass, ok = value.(*LoxClass)
 		if !ok {
-			panic(&RuntimeError{stmt.Name, "Superclass must be a class."})
+			panic(&RuntimeError{
+				token:   stmt.Name,
+				message: "Superclass must be a class.",
+			})
 		}
 	}
 
@@ -454,6 +568,8 @@ func (i *Interpreter) VisitClassStmt(stmt *Class) interface{} {
 			declaration:   function,
 			closure:       i.environment,
 			isInitializer: isInitializer,
+			locals:        i.locals,
+			source:        i.source,
 		}
 	}
 
@@ -471,19 +587,30 @@ func (i *Interpreter) VisitClassStmt(stmt *Class) interface{} {
 	return nil
 }
 
+type propertyGetter interface {
+	Get(name Token) interface{}
+}
+
+type propertySetter interface {
+	Set(name Token, value interface{})
+}
+
 func (i *Interpreter) VisitGetExpr(expr *Get) interface{} {
 	object := i.Evaluate(expr.Object)
 
-	if instance, ok := object.(*LoxInstance); ok {
+	if instance, ok := object.(propertyGetter); ok {
 		return instance.Get(expr.Name)
 	}
-	panic(RuntimeError{expr.Name, "Only instances have properties."})
+	panic(&RuntimeError{
+		token:   expr.Name,
+		message: "Only instances have properties.",
+	})
 }
 
 func (i *Interpreter) VisitSetExpr(expr *Set) interface{} {
 	object := i.Evaluate(expr.Object)
 
-	if instance, ok := object.(*LoxInstance); ok {
+	if instance, ok := object.(propertySetter); ok {
 		value := i.Evaluate(expr.Value)
 		instance.Set(expr.Name, value)
 		return value
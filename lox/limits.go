@@ -0,0 +1,75 @@
+package lox
+
+import "errors"
+
+// DefaultMaxCallDepth is the call depth used when Limits.MaxCallDepth is
+// zero. It keeps runaway recursion well clear of the Go stack limit.
+const DefaultMaxCallDepth = 10000
+
+// ErrLimitExceeded is wrapped by the runtime error raised when a script
+// exceeds one of its Limits.
+var ErrLimitExceeded = errors.New("lox: resource limit exceeded")
+
+// Limits bounds the resources a script may use. Zero fields mean no limit,
+// except MaxCallDepth which falls back to DefaultMaxCallDepth.
+type Limits struct {
+	// MaxCallDepth is the deepest chain of nested calls allowed.
+	MaxCallDepth int
+	// MaxSteps is the number of statements and expressions each Run, Eval,
+	// Interpret or Call may execute. It is enforced at loop iterations and
+	// calls, since straight-line code cannot run away.
+	MaxSteps int64
+	// MaxStringLength is the longest string concatenation may produce.
+	MaxStringLength int
+	// MaxAllocations is a per-run allocation budget: the number of class
+	// instances each Run, Eval, Interpret or Call may create. Instances that
+	// are no longer in use still count, so it bounds allocation work rather
+	// than memory held.
+	MaxAllocations int64
+}
+
+func (i *Interpreter) limitError(token Token, message string) *RuntimeError {
+	return &RuntimeError{
+		token:   token,
+		message: message,
+		cause:   ErrLimitExceeded,
+	}
+}
+
+func (i *Interpreter) checkSteps(token Token) {
+	if i.limits.MaxSteps > 0 && i.steps > i.limits.MaxSteps {
+		panic(i.limitError(token, "Step limit exceeded."))
+	}
+}
+
+func (i *Interpreter) enterCall(paren Token, frame Frame) {
+	maxDepth := i.limits.MaxCallDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxCallDepth
+	}
+	if len(i.frames) >= maxDepth {
+		panic(i.limitError(paren, "Stack overflow."))
+	}
+	i.frames = append(i.frames, frame)
+}
+
+func (i *Interpreter) leaveCall() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+func (i *Interpreter) checkStringLength(token Token, length int) {
+	if i.limits.MaxStringLength > 0 && length > i.limits.MaxStringLength {
+		panic(i.limitError(token, "String length limit exceeded."))
+	}
+}
+
+// trackAllocation counts a new instance against MaxAllocations.
+func (i *Interpreter) trackAllocation(token Token, instance *LoxInstance) {
+	if i.limits.MaxAllocations <= 0 {
+		return
+	}
+	if i.allocations >= i.limits.MaxAllocations {
+		panic(i.limitError(token, "Allocation limit exceeded."))
+	}
+	i.allocations++
+}
@@ -0,0 +1,70 @@
+package lox
+
+import (
+	"errors"
+	"testing"
+)
+
+// checkLimit runs each source in a fresh interpreter with limits and
+// checks that it either succeeds or fails with the given limit message.
+func checkLimit(t *testing.T, limits Limits, tests map[string]string) {
+	t.Helper()
+	for source, want := range tests {
+		err := New(Options{Limits: limits}).Run(source)
+		if want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", source, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: error = %v, want ErrLimitExceeded", source, err)
+			continue
+		}
+		if got := runtimeMessage(t, err); got != want {
+			t.Errorf("%s: error = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	checkLimit(t, Limits{MaxSteps: 1000}, map[string]string{
+		`while (true) {}`:                       "Step limit exceeded.",
+		`fun f() { f(); } f();`:                 "Step limit exceeded.",
+		`for (var i = 0; i < 10; i = i + 1) {}`: "",
+	})
+}
+
+func TestStringLengthLimit(t *testing.T) {
+	checkLimit(t, Limits{MaxStringLength: 8}, map[string]string{
+		`var s = "abcd"; s = s + s;`:            "",
+		`var s = "abcd"; s = s + s + "!";`:      "String length limit exceeded.",
+		`var s = "ab"; while (true) s = s + s;`: "String length limit exceeded.",
+	})
+}
+
+func TestAllocationLimit(t *testing.T) {
+	checkLimit(t, Limits{MaxAllocations: 3}, map[string]string{
+		`class C {} C(); C(); C();`:       "",
+		`class C {} C(); C(); C(); C();`:  "Allocation limit exceeded.",
+		`class C {} while (true) C();`:    "Allocation limit exceeded.",
+		`class C {} var keep = C(); C();`: "",
+	})
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	checkLimit(t, Limits{MaxCallDepth: 50}, map[string]string{
+		`fun f(n) { if (n > 1) f(n - 1); } f(50);`: "",
+		`fun f(n) { if (n > 1) f(n - 1); } f(51);`: "Stack overflow.",
+		`fun f() { f(); } f();`:                    "Stack overflow.",
+	})
+}
+
+func TestLimitsResetForEachRun(t *testing.T) {
+	interpreter := New(Options{Limits: Limits{MaxSteps: 1000, MaxAllocations: 2}})
+	for run := 0; run < 3; run++ {
+		if err := interpreter.Run(`class C {} C(); C(); for (var i = 0; i < 80; i = i + 1) {}`); err != nil {
+			t.Fatalf("run %d: %v", run+1, err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Options configures an Interpreter created with New or NewInterpreter.
+type Options struct {
+	// Stdout receives the output of print statements. Defaults to os.Stdout.
+	Stdout io.Writer
+	// Stdin is read by the readLine native (CapStdin). Defaults to os.Stdin.
+	Stdin io.Reader
+	// Timeout bounds the wall-clock time of each Run, Eval, Interpret or
+	// Call. Zero means no limit.
+	Timeout time.Duration
+	// Limits bounds call depth, work, string size and allocations.
+	Limits Limits
+	// Capabilities selects the groups of privileged natives installed as
+	// globals. Zero installs none; DefaultCapabilities is a safe start.
+	Capabilities Capability
+	// FSRoot is the directory filesystem natives are confined to. Defaults
+	// to the working directory.
+	FSRoot string
+	// Trace, when set, receives a log of scopes entered and variables
+	// defined, resolved and looked up, for debugging the interpreter.
+	Trace io.Writer
+	// Audit, when set, sees every privileged native call before it runs and
+	// may deny it by returning an error, which the script sees as a runtime
+	// error.
+	Audit func(AuditEvent) error
+}
+
+// New returns an interpreter ready to run Lox source. It is the same as
+// NewInterpreter.
+func New(opts Options) *Interpreter {
+	return NewInterpreter(opts)
+}
+
+// Run scans, parses, resolves and executes source against the
+// interpreter's globals. Errors are returned as *ScanError, *ParseError,
+// *ResolveError or *RuntimeError; several scan errors are joined.
+func (i *Interpreter) Run(source string) error {
+	return i.RunContext(i.ctx, source)
+}
+
+// RunContext is like Run but stops executing once ctx is done.
+func (i *Interpreter) RunContext(ctx context.Context, source string) error {
+	return i.RunFileContext(ctx, "", source)
+}
+
+// RunFile is like Run for source read from the named file. Runtime errors
+// raised in its functions remember the file, so they can be reported
+// against it even when a later file is running.
+func (i *Interpreter) RunFile(name, source string) error {
+	return i.RunFileContext(i.ctx, name, source)
+}
+
+// RunFileContext is like RunFile but stops executing once ctx is done.
+func (i *Interpreter) RunFileContext(ctx context.Context, name, source string) error {
+	_, _, err := i.run(ctx, &chunkSource{name: name, text: source})
+	return err
+}
+
+// run executes source as a new chunk. If the chunk ends with an expression
+// statement, its value is returned and isExpression is true.
+func (i *Interpreter) run(ctx context.Context, source *chunkSource) (last interface{}, isExpression bool, err error) {
+	statements, err := parseProgram(source.text)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resolver := i.newResolver()
+	if err := resolver.Resolve(statements); err != nil {
+		return nil, false, err
+	}
+	defer i.withLocals(resolver.Locals())()
+	defer i.withSource(source)()
+	last, err = i.interpret(ctx, statements)
+	if err != nil || len(statements) == 0 {
+		return nil, false, err
+	}
+	if _, isExpression = statements[len(statements)-1].(*Expression); !isExpression {
+		last = nil
+	}
+	return last, isExpression, nil
+}
+
+// withLocals installs a newly loaded chunk's resolution table and returns a
+// function restoring the previous one. Functions declared in the chunk keep
+// a reference to the table, so it is collected along with them instead of
+// accumulating in the interpreter.
+func (i *Interpreter) withLocals(locals Locals) func() {
+	previous := i.locals
+	i.locals = locals
+	return func() {
+		i.locals = previous
+	}
+}
+
+// withSource makes source the chunk runtime errors are attributed to and
+// returns a function restoring the previous one.
+func (i *Interpreter) withSource(source *chunkSource) func() {
+	previous := i.source
+	i.source = source
+	return func() {
+		i.source = previous
+	}
+}
+
+// Eval evaluates a single expression and returns its value.
+func (i *Interpreter) Eval(source string) (Value, error) {
+	return i.EvalContext(i.ctx, source)
+}
+
+// EvalContext is like Eval but stops evaluating once ctx is done.
+func (i *Interpreter) EvalContext(ctx context.Context, source string) (value Value, err error) {
+	scanner := NewScanner(source)
+	tokens, scanErrors := scanner.ScanTokens()
+	if len(scanErrors) > 0 {
+		return Value{}, errors.Join(scanErrors...)
+	}
+
+	expr, err := NewParser(tokens).ParseExpression()
+	if err != nil {
+		return Value{}, err
+	}
+
+	resolver := i.newResolver()
+	if err := resolver.Resolve(expr); err != nil {
+		return Value{}, err
+	}
+	defer i.withLocals(resolver.Locals())()
+	defer i.withSource(&chunkSource{text: source})()
+
+	defer i.withContext(ctx)()
+	defer i.attributeError(&err)
+	defer catchRuntimeError(&err)
+	return NewValue(i.Evaluate(expr)), nil
+}
+
+// Global returns the value of a global variable.
+func (i *Interpreter) Global(name string) (Value, bool) {
+	value, ok := i.globals.values[name]
+	return NewValue(value), ok
+}
+
+// SetGlobal defines a global variable, converting value with ToLox.
+func (i *Interpreter) SetGlobal(name string, value interface{}) error {
+	converted, err := ToLox(value)
+	if err != nil {
+		return err
+	}
+	i.globals.Define(name, converted)
+	return nil
+}
+
+// Call invokes a Lox function, class or native with arguments converted by
+// ToLox. callee may be a Value or a raw Lox value.
+func (i *Interpreter) Call(callee interface{}, args ...interface{}) (Value, error) {
+	return i.CallContext(i.ctx, callee, args...)
+}
+
+// CallContext is like Call but stops the callee once ctx is done.
+func (i *Interpreter) CallContext(ctx context.Context, callee interface{}, args ...interface{}) (result Value, err error) {
+	if value, ok := callee.(Value); ok {
+		callee = value.raw
+	}
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		return Value{}, fmt.Errorf("can only call functions and classes, got %s", typeName(callee))
+	}
+
+	arguments := make([]interface{}, len(args))
+	for index, arg := range args {
+		if arguments[index], err = ToLox(arg); err != nil {
+			return Value{}, fmt.Errorf("argument %d: %w", index+1, err)
+		}
+	}
+
+	defer i.withContext(ctx)()
+	defer catchRuntimeError(&err)
+	checkArity(function, Token{}, len(arguments))
+	return NewValue(i.call(function, Token{}, Span{}, arguments)), nil
+}
+
+func parseProgram(source string) ([]Stmt, error) {
+	scanner := NewScanner(source)
+	tokens, scanErrors := scanner.ScanTokens()
+	if len(scanErrors) > 0 {
+		return nil, errors.Join(scanErrors...)
+	}
+	return NewParser(tokens).Parse()
+}
+
+// Check scans, parses and resolves source without running it and returns
+// every diagnostic found. Parsing continues after scan errors so both kinds
+// are reported; resolution only runs on a program that parsed.
+func Check(source string) []error {
+	tokens, diagnostics := NewScanner(source).ScanTokens()
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		return append(diagnostics, unjoin(err)...)
+	}
+	if err := NewResolver().Resolve(statements); err != nil {
+		diagnostics = append(diagnostics, unjoin(err)...)
+	}
+	return diagnostics
+}
+
+// unjoin returns the errors joined in err, or err alone.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -1,4 +1,6 @@
-package main
+package lox
+
+import "sort"
 
 type LoxClass struct {
 	name       string
@@ -24,8 +26,27 @@ func (c *LoxClass) FindMethod(name string) *LoxFunction {
 	return nil
 }
 
+// MethodNames returns the sorted names of the class's methods, including
+// inherited ones.
+func (c *LoxClass) MethodNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for class := c; class != nil; class = class.superclass {
+		for name := range class.methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (c *LoxClass) Call(interpreter *Interpreter, arguments []interface{}) interface{} {
 	instance := NewLoxInstance(c)
+	instance.interpreter = interpreter
+	interpreter.trackAllocation(Token{}, instance)
 	initializer := c.FindMethod("init")
 	if initializer != nil {
 		initializer.Bind(instance).Call(interpreter, arguments)
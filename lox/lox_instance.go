@@ -0,0 +1,82 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+)
+
+type LoxInstance struct {
+	class       *LoxClass
+	fields      map[string]interface{}
+	interpreter *Interpreter
+}
+
+var _ fmt.Stringer = (*LoxInstance)(nil)
+
+func NewLoxInstance(class *LoxClass) *LoxInstance {
+	return &LoxInstance{
+		class:  class,
+		fields: make(map[string]interface{}),
+	}
+}
+
+func (i *LoxInstance) String() string {
+	return fmt.Sprintf("%s instance", i.class.name)
+}
+
+func (i *LoxInstance) Class() *LoxClass {
+	return i.class
+}
+
+// FieldNames returns the sorted names of the instance's fields.
+func (i *LoxInstance) FieldNames() []string {
+	names := make([]string, 0, len(i.fields))
+	for name := range i.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Field returns the value of a field without looking at methods.
+func (i *LoxInstance) Field(name string) (interface{}, bool) {
+	value, ok := i.fields[name]
+	return value, ok
+}
+
+func (instance *LoxInstance) Get(name Token) interface{} {
+	if value, ok := instance.fields[name.Lexeme]; ok {
+		return value
+	}
+	method := instance.class.FindMethod(name.Lexeme)
+	if method != nil {
+		return method.Bind(instance)
+	}
+	panic(&RuntimeError{
+		token:   name,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+	})
+
+}
+
+func (i *LoxInstance) Set(name Token, value interface{}) {
+	i.fields[name.Lexeme] = value
+}
+
+// Invoke calls the named method, or a callable stored in the named field,
+// with arguments converted by ToLox.
+func (i *LoxInstance) Invoke(name string, args ...interface{}) (result Value, err error) {
+	if i.interpreter == nil {
+		return Value{}, fmt.Errorf("instance of %s is not attached to an interpreter", i.class.name)
+	}
+
+	var callee interface{}
+	func() {
+		defer catchRuntimeError(&err)
+		callee = i.Get(Token{Type: IDENTIFIER, Lexeme: name})
+	}()
+	if err != nil {
+		return Value{}, err
+	}
+	return i.interpreter.Call(callee, args...)
+}
@@ -0,0 +1,83 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type LoxList struct {
+	elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+func (l *LoxList) String() string {
+	parts := make([]string, len(l.elements))
+	for index, element := range l.elements {
+		parts[index] = Stringify(element)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (l *LoxList) Elements() []interface{} {
+	return l.elements
+}
+
+func (l *LoxList) Get(name Token) interface{} {
+	switch name.Lexeme {
+	case "length":
+		return float64(len(l.elements))
+	case "get":
+		return l.method("get", func(index float64) (interface{}, error) {
+			position, err := l.index(index)
+			if err != nil {
+				return nil, err
+			}
+			return l.elements[position], nil
+		})
+	case "set":
+		return l.method("set", func(index float64, value interface{}) (interface{}, error) {
+			position, err := l.index(index)
+			if err != nil {
+				return nil, err
+			}
+			l.elements[position] = value
+			return value, nil
+		})
+	case "push":
+		return l.method("push", func(value interface{}) {
+			l.elements = append(l.elements, value)
+		})
+	case "pop":
+		return l.method("pop", func() (interface{}, error) {
+			if len(l.elements) == 0 {
+				return nil, fmt.Errorf("Can't pop from an empty list.")
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last, nil
+		})
+	}
+	panic(&RuntimeError{
+		token:   name,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+	})
+}
+
+func (l *LoxList) method(name string, fn interface{}) *NativeFunction {
+	native, err := NewNativeFunction(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	return native
+}
+
+func (l *LoxList) index(index float64) (int, error) {
+	if index != math.Trunc(index) || index < 0 || index >= float64(len(l.elements)) {
+		return 0, fmt.Errorf("List index %s out of range.", Stringify(index))
+	}
+	return int(index), nil
+}
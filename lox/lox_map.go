@@ -0,0 +1,52 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type LoxMap struct {
+	entries map[string]interface{}
+}
+
+func NewLoxMap(entries map[string]interface{}) *LoxMap {
+	return &LoxMap{entries: entries}
+}
+
+func (m *LoxMap) String() string {
+	keys := m.Keys()
+	parts := make([]string, len(keys))
+	for index, key := range keys {
+		parts[index] = fmt.Sprintf("%s: %s", key, Stringify(m.entries[key]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (m *LoxMap) Entries() map[string]interface{} {
+	return m.entries
+}
+
+// Keys returns the map's keys in sorted order.
+func (m *LoxMap) Keys() []string {
+	keys := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *LoxMap) Get(name Token) interface{} {
+	if value, ok := m.entries[name.Lexeme]; ok {
+		return value
+	}
+	panic(&RuntimeError{
+		token:   name,
+		message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+	})
+}
+
+func (m *LoxMap) Set(name Token, value interface{}) {
+	m.entries[name.Lexeme] = value
+}
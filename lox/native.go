@@ -0,0 +1,344 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterFunc defines a global native function backed by fn, which must be
+// a Go function. Arguments are converted from Lox values to fn's parameter
+// types and results back to Lox values. Trailing pointer parameters are
+// optional and receive nil when omitted, and variadic functions accept any
+// number of trailing arguments. A non-nil error result, which must come
+// last, is raised as a Lox runtime error.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	native, err := NewNativeFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	i.globals.Define(name, native)
+	return nil
+}
+
+// NewNativeFunction wraps a Go function as a Lox callable. See RegisterFunc.
+func NewNativeFunction(name string, fn interface{}) (*NativeFunction, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return nil, fmt.Errorf("native %s: expected a function, got %T", name, fn)
+	}
+	return wrapFunc(name, value)
+}
+
+func wrapFunc(name string, fn reflect.Value) (*NativeFunction, error) {
+	fnType := fn.Type()
+	if err := checkResults(fnType); err != nil {
+		return nil, fmt.Errorf("native %s: %w", name, err)
+	}
+
+	params := fnType.NumIn()
+	if fnType.IsVariadic() {
+		params--
+	}
+	required := params
+	for !fnType.IsVariadic() && required > 0 && fnType.In(required-1).Kind() == reflect.Pointer {
+		required--
+	}
+
+	native := &NativeFunction{
+		name:     name,
+		arity:    required,
+		optional: params - required,
+		variadic: fnType.IsVariadic(),
+	}
+	native.function = func(arguments []interface{}) interface{} {
+		in := make([]reflect.Value, 0, fnType.NumIn())
+		for index := 0; index < params; index++ {
+			paramType := fnType.In(index)
+			if index >= len(arguments) {
+				in = append(in, reflect.Zero(paramType))
+				continue
+			}
+			in = append(in, native.argument(index, arguments[index], paramType))
+		}
+		if fnType.IsVariadic() {
+			elemType := fnType.In(params).Elem()
+			for index := params; index < len(arguments); index++ {
+				in = append(in, native.argument(index, arguments[index], elemType))
+			}
+		}
+		return returnValue(fn.Call(in))
+	}
+	return native, nil
+}
+
+func (n *NativeFunction) argument(index int, value interface{}, paramType reflect.Type) reflect.Value {
+	converted, err := FromLox(value, paramType)
+	if err != nil {
+		panic(&RuntimeError{
+			message: fmt.Sprintf("Argument %d to '%s': %s", index+1, n.name, err),
+		})
+	}
+	return converted
+}
+
+func checkResults(fnType reflect.Type) error {
+	switch fnType.NumOut() {
+	case 0, 1:
+		return nil
+	case 2:
+		if fnType.Out(1) != errorType {
+			return fmt.Errorf("second result must be an error, got %s", fnType.Out(1))
+		}
+		return nil
+	}
+	return fmt.Errorf("expected at most two results, got %d", fnType.NumOut())
+}
+
+func returnValue(results []reflect.Value) interface{} {
+	if len(results) == 0 {
+		return nil
+	}
+	last := results[len(results)-1]
+	if last.Type() == errorType {
+		if !last.IsNil() {
+			// A runtime error from a nested Call keeps its token and cause.
+			if runtimeErr, ok := last.Interface().(*RuntimeError); ok {
+				panic(runtimeErr)
+			}
+			panic(&RuntimeError{message: last.Interface().(error).Error()})
+		}
+		results = results[:len(results)-1]
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	value, err := ToLox(results[0].Interface())
+	if err != nil {
+		panic(&RuntimeError{message: err.Error()})
+	}
+	return value
+}
+
+// ToLox converts a Go value into the equivalent Lox value. Numbers become
+// float64, slices become lists, string-keyed maps become maps, functions
+// become natives, and structs and HostObjects become host instances. Lox
+// values are passed through unchanged.
+func ToLox(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, float64, string, LoxCallable, *LoxInstance, *LoxList, *LoxMap, *HostInstance:
+		return value, nil
+	case HostObject:
+		return NewHostInstance(value)
+	case Value:
+		return v.raw, nil
+	}
+	return toLox(reflect.ValueOf(value))
+}
+
+func toLox(value reflect.Value) (interface{}, error) {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		if value.Kind() == reflect.Pointer && value.Elem().Kind() == reflect.Struct {
+			return NewHostInstance(value.Interface())
+		}
+		return ToLox(value.Elem().Interface())
+	case reflect.Struct:
+		return NewHostInstance(value.Interface())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+		list := NewLoxList(make([]interface{}, 0, value.Len()))
+		for index := 0; index < value.Len(); index++ {
+			element, err := ToLox(value.Index(index).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list.elements = append(list.elements, element)
+		}
+		return list, nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert %s to a Lox value: keys must be strings", value.Type())
+		}
+		if value.IsNil() {
+			return nil, nil
+		}
+		entries := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			entry, err := ToLox(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			entries[iter.Key().String()] = entry
+		}
+		return NewLoxMap(entries), nil
+	case reflect.Func:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return wrapFunc(value.Type().String(), value)
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Lox value", value.Type())
+}
+
+// FromLox converts a Lox value into a Go value of the given type.
+func FromLox(value interface{}, target reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s but got nil", describeType(target))
+	}
+	if reflect.TypeOf(value).AssignableTo(target) {
+		return reflect.ValueOf(value), nil
+	}
+	if host, ok := value.(*HostInstance); ok {
+		if host.value.Type().AssignableTo(target) {
+			return host.value, nil
+		}
+		if host.value.Kind() == reflect.Pointer && host.value.Elem().Type().AssignableTo(target) {
+			return host.value.Elem(), nil
+		}
+	}
+
+	switch target.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b).Convert(target), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := value.(float64); ok {
+			return reflect.ValueOf(n).Convert(target), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := value.(float64); ok {
+			if n != math.Trunc(n) {
+				return reflect.Value{}, fmt.Errorf("expected an integer but got %s", Stringify(n))
+			}
+			// Check the range as a float: converting first would wrap.
+			if min, max := integerRange(target); n < min || n >= max {
+				return reflect.Value{}, fmt.Errorf("%s is out of range for %s", Stringify(n), target)
+			}
+			converted := reflect.New(target).Elem()
+			if target.Kind() >= reflect.Uint {
+				converted.SetUint(uint64(n))
+			} else {
+				converted.SetInt(int64(n))
+			}
+			return converted, nil
+		}
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return reflect.ValueOf(s).Convert(target), nil
+		}
+	case reflect.Pointer:
+		elem, err := FromLox(value, target.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		pointer := reflect.New(target.Elem())
+		pointer.Elem().Set(elem)
+		return pointer, nil
+	case reflect.Slice:
+		if list, ok := value.(*LoxList); ok {
+			slice := reflect.MakeSlice(target, 0, len(list.elements))
+			for _, element := range list.elements {
+				converted, err := FromLox(element, target.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice = reflect.Append(slice, converted)
+			}
+			return slice, nil
+		}
+	case reflect.Map:
+		if m, ok := value.(*LoxMap); ok && target.Key().Kind() == reflect.String {
+			result := reflect.MakeMapWithSize(target, len(m.entries))
+			for key, entry := range m.entries {
+				converted, err := FromLox(entry, target.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				result.SetMapIndex(reflect.ValueOf(key).Convert(target.Key()), converted)
+			}
+			return result, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s but got %s", describeType(target), typeName(value))
+}
+
+// integerRange returns the values an integer type can hold as floats, min
+// inclusive and max exclusive. Both are powers of two, so they are exact.
+func integerRange(t reflect.Type) (min, max float64) {
+	bits := t.Bits()
+	if t.Kind() >= reflect.Uint {
+		return 0, math.Ldexp(1, bits)
+	}
+	return -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map:
+		return "a map"
+	case reflect.Pointer:
+		return describeType(t.Elem())
+	}
+	return t.String()
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case *LoxList:
+		return "a list"
+	case *LoxMap:
+		return "a map"
+	case *LoxClass:
+		return "a class"
+	case *LoxInstance, *HostInstance:
+		return "an instance"
+	case LoxCallable:
+		return "a function"
+	}
+	return fmt.Sprintf("%T", value)
+}
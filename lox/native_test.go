@@ -0,0 +1,268 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runNative runs source with fns registered as natives and returns what it
+// printed.
+func runNative(t *testing.T, source string, fns map[string]interface{}) (string, error) {
+	t.Helper()
+	var output bytes.Buffer
+	interpreter := New(Options{Stdout: &output})
+	for name, fn := range fns {
+		if err := interpreter.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("RegisterFunc(%q): %v", name, err)
+		}
+	}
+	err := interpreter.Run(source)
+	return output.String(), err
+}
+
+// runtimeMessage returns the message of the runtime error err holds.
+func runtimeMessage(t *testing.T, err error) string {
+	t.Helper()
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+	return runtimeErr.Message()
+}
+
+func TestRegisterFuncOptionalArguments(t *testing.T) {
+	fns := map[string]interface{}{
+		"greet": func(name string, greeting *string) string {
+			if greeting == nil {
+				return "hello " + name
+			}
+			return *greeting + " " + name
+		},
+	}
+
+	output, err := runNative(t, `print greet("ada"); print greet("ada", "hi"); print greet("ada", nil);`, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hello ada\nhi ada\nhello ada\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	for source, want := range map[string]string{
+		`greet();`:              "Expected 1 to 2 arguments but got 0.",
+		`greet("a", "b", "c");`: "Expected 1 to 2 arguments but got 3.",
+	} {
+		_, err := runNative(t, source, fns)
+		if got := runtimeMessage(t, err); got != want {
+			t.Errorf("%s: error = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestRegisterFuncVariadic(t *testing.T) {
+	fns := map[string]interface{}{
+		"sum": func(numbers ...float64) float64 {
+			total := 0.0
+			for _, n := range numbers {
+				total += n
+			}
+			return total
+		},
+		"join": func(separator string, parts ...string) string {
+			return strings.Join(parts, separator)
+		},
+	}
+
+	output, err := runNative(t, `print sum(); print sum(1, 2, 3); print join("-"); print join("-", "a", "b");`, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0\n6\n\na-b\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	_, err = runNative(t, `join();`, fns)
+	if got, want := runtimeMessage(t, err), "Expected at least 1 arguments but got 0."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	_, err = runNative(t, `sum(1, "two");`, fns)
+	if got, want := runtimeMessage(t, err), "Argument 2 to 'sum': expected a number but got a string"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterFuncErrorResult(t *testing.T) {
+	fns := map[string]interface{}{
+		"divide": func(a, b float64) (float64, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"check": func(ok bool) error {
+			if !ok {
+				return errors.New("check failed")
+			}
+			return nil
+		},
+	}
+
+	output, err := runNative(t, `print divide(6, 3); print check(true);`, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2\nnil\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	for source, want := range map[string]string{
+		`divide(1, 0);`: "division by zero",
+		`check(false);`: "check failed",
+	} {
+		_, err := runNative(t, source, fns)
+		if got := runtimeMessage(t, err); got != want {
+			t.Errorf("%s: error = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestRegisterFuncRejectsBadSignatures(t *testing.T) {
+	interpreter := New(Options{})
+	for name, fn := range map[string]interface{}{
+		"notAFunction": 42,
+		"errorFirst":   func() (error, float64) { return nil, 0 },
+		"threeResults": func() (float64, float64, error) { return 0, 0, nil },
+	} {
+		if err := interpreter.RegisterFunc(name, fn); err == nil {
+			t.Errorf("RegisterFunc(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestFromLoxIntegerRange(t *testing.T) {
+	tests := []struct {
+		value  float64
+		target interface{}
+		ok     bool
+	}{
+		{255, uint8(0), true},
+		{256, uint8(0), false},
+		{-1, uint8(0), false},
+		{-128, int8(0), true},
+		{127, int8(0), true},
+		{128, int8(0), false},
+		{-129, int8(0), false},
+		{math.Ldexp(1, 62), int64(0), true},
+		{math.Ldexp(1, 63), int64(0), false},
+		{-math.Ldexp(1, 63), int64(0), true},
+		{math.Ldexp(1, 64), uint64(0), false},
+		{1e20, 0, false},
+		{1.5, 0, false},
+		{math.Inf(1), 0, false},
+		{math.NaN(), 0, false},
+	}
+	for _, test := range tests {
+		target := reflect.TypeOf(test.target)
+		converted, err := FromLox(test.value, target)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("FromLox(%v, %s) = %v, want an error", test.value, target, converted)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FromLox(%v, %s): %v", test.value, target, err)
+			continue
+		}
+		if back, err := ToLox(converted.Interface()); err != nil || back != test.value {
+			t.Errorf("FromLox(%v, %s) = %v, want %v", test.value, target, back, test.value)
+		}
+	}
+}
+
+func TestToLoxConvertsSlicesAndMaps(t *testing.T) {
+	value, err := ToLox(map[string][]int{"primes": {2, 3, 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := value.(*LoxMap)
+	if !ok {
+		t.Fatalf("ToLox returned %T, want *LoxMap", value)
+	}
+	list, ok := m.Entries()["primes"].(*LoxList)
+	if !ok {
+		t.Fatalf("primes is %T, want *LoxList", m.Entries()["primes"])
+	}
+	if want := []interface{}{2.0, 3.0, 5.0}; !reflect.DeepEqual(list.Elements(), want) {
+		t.Errorf("primes = %v, want %v", list.Elements(), want)
+	}
+
+	for _, nilValue := range []interface{}{[]string(nil), map[string]int(nil)} {
+		if value, err := ToLox(nilValue); err != nil || value != nil {
+			t.Errorf("ToLox(%#v) = %v, %v; want nil", nilValue, value, err)
+		}
+	}
+	if _, err := ToLox(map[int]string{1: "one"}); err == nil {
+		t.Error("ToLox of a map with int keys succeeded, want an error")
+	}
+}
+
+func TestFromLoxConvertsListsAndMaps(t *testing.T) {
+	list := NewLoxList([]interface{}{1.0, 2.0})
+	converted, err := FromLox(list, reflect.TypeOf([]int{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(converted.Interface(), want) {
+		t.Errorf("FromLox(list) = %v, want %v", converted.Interface(), want)
+	}
+
+	m := NewLoxMap(map[string]interface{}{"a": NewLoxList([]interface{}{"x"}), "b": nil})
+	converted, err = FromLox(m, reflect.TypeOf(map[string][]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"a": {"x"}, "b": nil}; !reflect.DeepEqual(converted.Interface(), want) {
+		t.Errorf("FromLox(map) = %v, want %v", converted.Interface(), want)
+	}
+
+	if _, err := FromLox(NewLoxList([]interface{}{1.0, "two"}), reflect.TypeOf([]float64{})); err == nil {
+		t.Error("FromLox of a mixed list into []float64 succeeded, want an error")
+	}
+	if _, err := FromLox(1.0, reflect.TypeOf([]float64{})); err == nil {
+		t.Error("FromLox of a number into []float64 succeeded, want an error")
+	}
+}
+
+func TestNativeRoundTripsThroughScript(t *testing.T) {
+	fns := map[string]interface{}{
+		"split": func(s string) []string {
+			return strings.Fields(s)
+		},
+		"counts": func(words []string) map[string]int {
+			counts := map[string]int{}
+			for _, word := range words {
+				counts[word]++
+			}
+			return counts
+		},
+		"total": func(counts map[string]int) int {
+			total := 0
+			for _, count := range counts {
+				total += count
+			}
+			return total
+		},
+	}
+	output, err := runNative(t, `print total(counts(split("a b a")));`, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "3\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
@@ -1,6 +1,7 @@
-package main
+package lox
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -8,6 +9,7 @@ type Parser struct {
 	tokens           []Token
 	current          int
 	currentClassName *Token
+	errors           []error
 }
 
 func NewParser(tokens []Token) *Parser {
@@ -17,26 +19,38 @@ func NewParser(tokens []Token) *Parser {
 	}
 }
 
-func (p *Parser) declaration() (Stmt, error) {
-	if p.match(CLASS) {
-		return p.classDeclaration()
-	}
-	if p.match(FUN) {
-		return p.function("function")
-	}
-	if p.match(VAR) {
-		return p.varDeclaration()
-	}
-	stmt, err := p.statement()
-	if err != nil {
+// declaration parses one declaration or statement. On a syntax error it
+// records the error, skips to the next statement boundary and returns nil.
+func (p *Parser) declaration() Stmt {
+	var stmt Stmt
+	var err error
+	switch {
+	case p.match(CLASS):
+		stmt, err = p.classDeclaration()
+	case p.match(FUN):
+		stmt, err = p.function("function")
+	case p.match(VAR):
+		stmt, err = p.varDeclaration()
+	default:
+		stmt, err = p.statement()
+	}
+	if err != nil {
+		p.errors = append(p.errors, err)
 		p.synchronize()
-		return nil, err
+		return nil
 	}
-	return stmt, nil
+	return stmt
+}
+
+// report records an error the parser can carry on past without losing its
+// place, such as an invalid assignment target.
+func (p *Parser) report(token Token, message string) {
+	p.errors = append(p.errors, NewParseError(token, message))
 }
 
 func (p *Parser) varDeclaration() (Stmt, error) {
-	name, err := p.consume(IDENTIFIER, "expect variable name")
+	start := p.previous()
+	name, err := p.consume(IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		return nil, err
 	}
@@ -50,12 +64,13 @@ func (p *Parser) varDeclaration() (Stmt, error) {
 		initializer = init
 	}
 
-	_, err = p.consume(SEMICOLON, "expect ';' after variable declaration")
+	_, err = p.consume(SEMICOLON, "Expect ';' after variable declaration.")
 	if err != nil {
 		return nil, err
 	}
 
 	return &Var{
+		Spanned:     p.spanFrom(start),
 		Name:        *name,
 		Initializer: initializer,
 	}, nil
@@ -67,29 +82,44 @@ func (p *Parser) consume(tokenType TokenType, message string) (*Token, error) {
 		token := p.advance()
 		return &token, nil
 	}
-	return nil, fmt.Errorf(message)
+	err := NewParseError(p.peek(), message)
+	if tokenType == SEMICOLON && p.current > 0 {
+		err.help = fmt.Sprintf("add ';' after '%s'", p.previous().Lexeme)
+	}
+	return nil, err
 }
 
-func (p *Parser) parse() ([]Stmt, error) {
+// Parse parses a whole program into a list of statements. After a syntax
+// error it resumes at the next statement, so the error returned joins a
+// *ParseError for every mistake in the program; the statements that did
+// parse are returned along with it.
+func (p *Parser) Parse() ([]Stmt, error) {
 	var statements []Stmt
 	for !p.isAtEnd() {
-		stmt, err := p.declaration()
-		if err != nil {
-			return nil, err
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
 		}
-		statements = append(statements, stmt)
 	}
-	return statements, nil
+	return statements, errors.Join(p.errors...)
 }
 
-func (p *Parser) parseExpression() (Expr, error) {
+// ParseExpression parses a single expression.
+func (p *Parser) ParseExpression() (Expr, error) {
 	expr, err := p.expression()
 	if err != nil {
-		return nil, err
+		p.errors = append(p.errors, err)
+	}
+	if len(p.errors) > 0 {
+		return nil, errors.Join(p.errors...)
 	}
 	return expr, nil
 }
 
+// AtEnd reports whether every token has been consumed.
+func (p *Parser) AtEnd() bool {
+	return p.isAtEnd()
+}
+
 func (p *Parser) statement() (Stmt, error) {
 	if p.match(IF) {
 		return p.ifStatement()
@@ -113,6 +143,7 @@ func (p *Parser) statement() (Stmt, error) {
 }
 
 func (p *Parser) ifStatement() (Stmt, error) {
+	start := p.previous()
 	_, err := p.consume(LEFT_PAREN, "Expect '(' after 'if'.")
 	if err != nil {
 		return nil, err
@@ -142,6 +173,8 @@ func (p *Parser) ifStatement() (Stmt, error) {
 	}
 
 	return &If{
+		Spanned:    p.spanFrom(start),
+		Keyword:    start,
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
@@ -149,34 +182,31 @@ func (p *Parser) ifStatement() (Stmt, error) {
 }
 
 func (p *Parser) printStatement() (Stmt, error) {
-
-	if p.match(SEMICOLON) {
-		return nil, fmt.Errorf("expect expression after 'print'")
-	}
-
+	start := p.previous()
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	_, err = p.consume(SEMICOLON, "expect ';' after value")
+	_, err = p.consume(SEMICOLON, "Expect ';' after value.")
 	if err != nil {
 		return nil, err
 	}
 
-	return &Print{Expression: expr}, nil
+	return &Print{Spanned: p.spanFrom(start), Keyword: start, Expression: expr}, nil
 }
 
 func (p *Parser) expressionStatement() (Stmt, error) {
+	start := p.peek()
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	_, err = p.consume(SEMICOLON, "expect ';' after expression")
+	_, err = p.consume(SEMICOLON, "Expect ';' after expression.")
 	if err != nil {
 		return nil, err
 	}
 
-	return &Expression{Expression: expr}, nil
+	return &Expression{Spanned: p.spanFrom(start), Expression: expr}, nil
 }
 
 func (p *Parser) expression() (Expr, error) {
@@ -184,6 +214,7 @@ func (p *Parser) expression() (Expr, error) {
 }
 
 func (p *Parser) and() (Expr, error) {
+	start := p.peek()
 	expr, err := p.equality()
 	if err != nil {
 		return nil, err
@@ -195,12 +226,13 @@ func (p *Parser) and() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &Logical{Left: expr, Operator: operator, Right: right}
+		expr = &Logical{Spanned: p.spanFrom(start), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
 
 func (p *Parser) or() (Expr, error) {
+	start := p.peek()
 	expr, err := p.and()
 	if err != nil {
 		return nil, err
@@ -211,12 +243,13 @@ func (p *Parser) or() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &Logical{Left: expr, Operator: operator, Right: right}
+		expr = &Logical{Spanned: p.spanFrom(start), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
 
 func (p *Parser) equality() (Expr, error) {
+	start := p.peek()
 	expr, err := p.comparison()
 	if err != nil {
 		return nil, err
@@ -228,6 +261,7 @@ func (p *Parser) equality() (Expr, error) {
 			return nil, err
 		}
 		expr = &Binary{
+			Spanned:  p.spanFrom(start),
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
@@ -237,6 +271,7 @@ func (p *Parser) equality() (Expr, error) {
 }
 
 func (p *Parser) comparison() (Expr, error) {
+	start := p.peek()
 	expr, err := p.term()
 	if err != nil {
 		return nil, err
@@ -248,6 +283,7 @@ func (p *Parser) comparison() (Expr, error) {
 			return nil, err
 		}
 		expr = &Binary{
+			Spanned:  p.spanFrom(start),
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
@@ -257,6 +293,7 @@ func (p *Parser) comparison() (Expr, error) {
 }
 
 func (p *Parser) term() (Expr, error) {
+	start := p.peek()
 	expr, err := p.factor()
 	if err != nil {
 		return nil, err
@@ -268,6 +305,7 @@ func (p *Parser) term() (Expr, error) {
 			return nil, err
 		}
 		expr = &Binary{
+			Spanned:  p.spanFrom(start),
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
@@ -277,6 +315,7 @@ func (p *Parser) term() (Expr, error) {
 }
 
 func (p *Parser) factor() (Expr, error) {
+	start := p.peek()
 	expr, err := p.unary()
 	if err != nil {
 		return nil, err
@@ -288,6 +327,7 @@ func (p *Parser) factor() (Expr, error) {
 			return nil, err
 		}
 		expr = &Binary{
+			Spanned:  p.spanFrom(start),
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
@@ -304,6 +344,7 @@ func (p *Parser) unary() (Expr, error) {
 			return nil, err
 		}
 		return &Unary{
+			Spanned:  p.spanFrom(operator),
 			Operator: operator,
 			Right:    right,
 		}, nil
@@ -312,24 +353,25 @@ func (p *Parser) unary() (Expr, error) {
 }
 
 func (p *Parser) primary() (Expr, error) {
+	start := p.peek()
 	if p.match(TRUE) {
-		return &Literal{Value: true}, nil
+		return &Literal{Spanned: p.spanFrom(start), Value: true, Token: p.previous()}, nil
 	}
 	if p.match(FALSE) {
-		return &Literal{Value: false}, nil
+		return &Literal{Spanned: p.spanFrom(start), Value: false, Token: p.previous()}, nil
 	}
 	if p.match(NIL) {
-		return &Literal{Value: nil}, nil
+		return &Literal{Spanned: p.spanFrom(start), Value: nil, Token: p.previous()}, nil
 	}
 	if p.match(NUMBER, STRING) {
-		return &Literal{Value: p.previous().Literal}, nil
+		return &Literal{Spanned: p.spanFrom(start), Value: p.previous().Literal, Token: p.previous()}, nil
 	}
 	if p.match(IDENTIFIER) {
 		token := p.previous()
 		if p.currentClassName != nil && token.Lexeme == p.currentClassName.Lexeme {
 			token = *p.currentClassName
 		}
-		return &Variable{Name: token}, nil
+		return &Variable{Spanned: p.spanFrom(start), Name: token}, nil
 	}
 	if p.match(LEFT_PAREN) {
 		expr, err := p.expression()
@@ -340,10 +382,10 @@ func (p *Parser) primary() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &Grouping{Expression: expr}, nil
+		return &Grouping{Spanned: p.spanFrom(start), Paren: start, Expression: expr}, nil
 	}
 	if p.match(THIS) {
-		return &This{Keyword: p.previous()}, nil
+		return &This{Spanned: p.spanFrom(start), Keyword: p.previous()}, nil
 	}
 	if p.match(SUPER) {
 		keyword := p.previous()
@@ -355,10 +397,10 @@ func (p *Parser) primary() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &Super{Keyword: keyword, Method: *method}, nil
+		return &Super{Spanned: p.spanFrom(start), Keyword: keyword, Method: *method}, nil
 	}
 
-	return nil, fmt.Errorf("Expect expression.")
+	return nil, NewParseError(p.peek(), "Expect expression.")
 }
 
 func (p *Parser) match(types ...TokenType) bool {
@@ -398,11 +440,13 @@ func (p *Parser) previous() Token {
 }
 
 func (p *Parser) assignment() (Expr, error) {
+	start := p.peek()
 	expr, err := p.or()
 	if err != nil {
 		return nil, err
 	}
 	if p.match(EQUAL) {
+		equals := p.previous()
 		value, err := p.assignment()
 		if err != nil {
 			return nil, err
@@ -410,39 +454,41 @@ func (p *Parser) assignment() (Expr, error) {
 
 		if get, ok := expr.(*Get); ok {
 			return &Set{
-				Object: get.Object,
-				Name:   get.Name,
-				Value:  value,
+				Spanned: p.spanFrom(start),
+				Object:  get.Object,
+				Name:    get.Name,
+				Value:   value,
 			}, nil
 		} else if v, ok := expr.(*Variable); ok {
 			return &Assign{
-				Name:  v.Name,
-				Value: value,
+				Spanned: p.spanFrom(start),
+				Name:    v.Name,
+				Value:   value,
 			}, nil
 		}
-		return nil, fmt.Errorf("invalid assignment target")
+		p.report(equals, "Invalid assignment target.")
 	}
 	return expr, nil
 }
 
 func (p *Parser) block() (Stmt, error) {
+	start := p.previous()
 	var statements []Stmt
 
 	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
-		decl, err := p.declaration()
-		if err != nil {
-			return nil, err
+		if decl := p.declaration(); decl != nil {
+			statements = append(statements, decl)
 		}
-		statements = append(statements, decl)
 	}
 	_, err := p.consume(RIGHT_BRACE, "Expect '}' after block.")
 	if err != nil {
 		return nil, err
 	}
-	return &Block{Statements: statements}, nil
+	return &Block{Spanned: p.spanFrom(start), Brace: start, Statements: statements}, nil
 }
 
 func (p *Parser) whileStatement() (Stmt, error) {
+	keyword := p.previous()
 	_, err := p.consume(LEFT_PAREN, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -464,12 +510,15 @@ func (p *Parser) whileStatement() (Stmt, error) {
 	}
 
 	return &While{
+		Spanned:   p.spanFrom(keyword),
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}, nil
 }
 
 func (p *Parser) forStatement() (Stmt, error) {
+	keyword := p.previous()
 	_, err := p.consume(LEFT_PAREN, "Expect '(' after 'for'.")
 	if err != nil {
 		return nil, err
@@ -520,9 +569,10 @@ func (p *Parser) forStatement() (Stmt, error) {
 
 	if increment != nil {
 		body = &Block{
+			Spanned: p.spanFrom(keyword),
 			Statements: []Stmt{
 				body,
-				&Expression{Expression: increment},
+				&Expression{Spanned: Spanned{span: increment.Span()}, Expression: increment},
 			},
 		}
 	}
@@ -530,11 +580,14 @@ func (p *Parser) forStatement() (Stmt, error) {
 		condition = &Literal{Value: true}
 	}
 	body = &While{
+		Spanned:   p.spanFrom(keyword),
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}
 	if initializer != nil {
 		body = &Block{
+			Spanned: p.spanFrom(keyword),
 			Statements: []Stmt{
 				initializer,
 				body,
@@ -546,6 +599,7 @@ func (p *Parser) forStatement() (Stmt, error) {
 }
 
 func (p *Parser) call() (Expr, error) {
+	start := p.peek()
 	expr, err := p.primary()
 	if err != nil {
 		return nil, err
@@ -553,7 +607,7 @@ func (p *Parser) call() (Expr, error) {
 
 	for {
 		if p.match(LEFT_PAREN) {
-			expr, err = p.finishCall(expr)
+			expr, err = p.finishCall(start, expr)
 			if err != nil {
 				return nil, err
 			}
@@ -563,8 +617,9 @@ func (p *Parser) call() (Expr, error) {
 				return nil, err
 			}
 			expr = &Get{
-				Object: expr,
-				Name:   *name,
+				Spanned: p.spanFrom(start),
+				Object:  expr,
+				Name:    *name,
 			}
 		} else {
 			break
@@ -573,14 +628,14 @@ func (p *Parser) call() (Expr, error) {
 	return expr, nil
 }
 
-func (p *Parser) finishCall(callee Expr) (Expr, error) {
+func (p *Parser) finishCall(start Token, callee Expr) (Expr, error) {
 	var arguments []Expr
 
 	if !p.check(RIGHT_PAREN) {
 		for {
 
 			if len(arguments) >= 255 {
-				return nil, fmt.Errorf("Can't have more than 255 arguments.")
+				p.report(p.peek(), "Can't have more than 255 arguments.")
 			}
 
 			expr, err := p.expression()
@@ -599,6 +654,7 @@ func (p *Parser) finishCall(callee Expr) (Expr, error) {
 	}
 
 	return &Call{
+		Spanned:   p.spanFrom(start),
 		Callee:    callee,
 		Paren:     *paren,
 		Arguments: arguments,
@@ -607,6 +663,11 @@ func (p *Parser) finishCall(callee Expr) (Expr, error) {
 }
 
 func (p *Parser) function(kind string) (*Function, error) {
+	// Functions start at the `fun` keyword, methods at their name.
+	start := p.peek()
+	if p.current > 0 && p.previous().Type == FUN {
+		start = p.previous()
+	}
 	var name Token
 	var err error
 
@@ -626,6 +687,9 @@ func (p *Parser) function(kind string) (*Function, error) {
 	parameters := make([]Token, 0)
 	if !p.check(RIGHT_PAREN) {
 		for {
+			if len(parameters) >= 255 {
+				p.report(p.peek(), "Can't have more than 255 parameters.")
+			}
 			param, err := p.consume(IDENTIFIER, "Expect parameter name.")
 			if err != nil {
 				return nil, err
@@ -654,9 +718,10 @@ func (p *Parser) function(kind string) (*Function, error) {
 	}
 
 	return &Function{
-		Name:   name,
-		Params: parameters,
-		Body:   blockStmt.(*Block).Statements,
+		Spanned: p.spanFrom(start),
+		Name:    name,
+		Params:  parameters,
+		Body:    blockStmt.(*Block).Statements,
 	}, nil
 }
 
@@ -671,9 +736,10 @@ func (p *Parser) returnStatement() (Stmt, error) {
 				return nil, err
 			}
 			value = &FunctionExpr{
-				Name:   funcStmt.Name,
-				Params: funcStmt.Params,
-				Body:   funcStmt.Body,
+				Spanned: funcStmt.Spanned,
+				Name:    funcStmt.Name,
+				Params:  funcStmt.Params,
+				Body:    funcStmt.Body,
 			}
 		} else {
 			var err error
@@ -689,7 +755,12 @@ func (p *Parser) returnStatement() (Stmt, error) {
 		return nil, err
 	}
 
-	return &ReturnStmt{Keyword: keyword, Value: value}, nil
+	return &ReturnStmt{Spanned: p.spanFrom(keyword), Keyword: keyword, Value: value}, nil
+}
+
+// spanFrom covers the source from start through the last consumed token.
+func (p *Parser) spanFrom(start Token) Spanned {
+	return spanBetween(start, p.previous())
 }
 
 func (p *Parser) synchronize() {
@@ -709,6 +780,7 @@ func (p *Parser) synchronize() {
 }
 
 func (p *Parser) classDeclaration() (Stmt, error) {
+	start := p.previous()
 	name, err := p.consume(IDENTIFIER, "Expect class name.")
 	if err != nil {
 		return nil, err
@@ -719,9 +791,12 @@ func (p *Parser) classDeclaration() (Stmt, error) {
 
 	var superclass Expr = nil
 	if p.match(LESS) {
-		p.consume(IDENTIFIER, "Expect superclass name.")
+		if _, err := p.consume(IDENTIFIER, "Expect superclass name."); err != nil {
+			return nil, err
+		}
 		superclass = &Variable{
-			Name: p.previous(),
+			Spanned: p.spanFrom(p.previous()),
+			Name:    p.previous(),
 		}
 	}
 
@@ -747,6 +822,7 @@ func (p *Parser) classDeclaration() (Stmt, error) {
 	p.currentClassName = previousClass
 
 	return &Class{
+		Spanned:    p.spanFrom(start),
 		Name:       *name,
 		Superclass: superclass,
 		Methods:    methods,
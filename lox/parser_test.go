@@ -0,0 +1,77 @@
+package lox
+
+import (
+	"reflect"
+	"testing"
+)
+
+// parseErrors parses source and returns the message of every error found.
+func parseErrors(t *testing.T, source string) []string {
+	t.Helper()
+	tokens, scanErrors := NewScanner(source).ScanTokens()
+	if len(scanErrors) > 0 {
+		t.Fatalf("scan errors: %v", scanErrors)
+	}
+	_, err := NewParser(tokens).Parse()
+	if err == nil {
+		return nil
+	}
+	var messages []string
+	for _, err := range unjoin(err) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestParserReportsEveryError(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{
+			"var = 1;\nprint ;\nfun (",
+			[]string{
+				"[line 1] Error at '=': Expect variable name.",
+				"[line 2] Error at ';': Expect expression.",
+				"[line 3] Error at end: Expect parameter name.",
+			},
+		},
+		{
+			"print 1\nvar x = ;\nprint x;\nif (x { print x; }",
+			[]string{
+				"[line 2] Error at 'var': Expect ';' after value.",
+				"[line 4] Error at '{': Expect ')' after if condition.",
+				"[line 4] Error at '}': Expect expression.",
+			},
+		},
+		{
+			"{\n  var a = 1;\n  a + ;\n}\nclass {}\nprint 1 = 2;",
+			[]string{
+				"[line 3] Error at ';': Expect expression.",
+				"[line 5] Error at '{': Expect class name.",
+				"[line 6] Error at '=': Invalid assignment target.",
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := parseErrors(t, test.source); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q:\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestParserKeepsStatementsAfterErrors(t *testing.T) {
+	tokens, _ := NewScanner("print ;\nprint 1;\nvar = 2;\nprint 3;").ScanTokens()
+	statements, err := NewParser(tokens).Parse()
+	if err == nil {
+		t.Fatal("Parse succeeded, want errors")
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want the 2 valid ones", len(statements))
+	}
+	for _, statement := range statements {
+		if _, ok := statement.(*Print); !ok {
+			t.Errorf("statement is %T, want *Print", statement)
+		}
+	}
+}
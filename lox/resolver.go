@@ -1,18 +1,25 @@
-package main
+package lox
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"reflect"
 )
 
+// Locals records, for each variable reference the resolver bound to a local
+// scope, how many scopes out from the reference the variable lives.
+// References missing from the table are globals.
+type Locals map[Expr]int
+
 type Resolver struct {
-	interpreter     *Interpreter
+	locals          Locals
+	trace           io.Writer
 	scopes          []map[string]bool
 	currentFunction FunctionType
 	globals         map[string]bool
-	inInitializer   map[string]bool
 	currentClass    ClassType
+	errors          []error
 }
 
 type FunctionType int
@@ -32,17 +39,29 @@ const (
 	METHOD
 )
 
-func NewResolver(interpreter *Interpreter) *Resolver {
+// NewResolver returns a resolver with an empty Locals table. The same
+// resolver may resolve several chunks into one table.
+func NewResolver() *Resolver {
 	return &Resolver{
-		interpreter:     interpreter,
+		locals:          make(Locals),
 		scopes:          make([]map[string]bool, 0),
 		currentFunction: NONE,
 		currentClass:    NO_CLASS,
 		globals:         make(map[string]bool),
-		inInitializer:   make(map[string]bool),
 	}
 }
 
+// Locals returns the resolution results for everything resolved so far.
+func (r *Resolver) Locals() Locals {
+	return r.locals
+}
+
+// error records a static error. Resolution carries on so every error in
+// the program is reported.
+func (r *Resolver) error(token Token, message string) {
+	r.errors = append(r.errors, NewResolveError(token, message))
+}
+
 func (r *Resolver) beginScope() {
 	r.scopes = append(r.scopes, make(map[string]bool))
 }
@@ -59,15 +78,9 @@ func (r *Resolver) declare(name *Token) {
 	}
 	scope := r.scopes[len(r.scopes)-1]
 	if _, exists := scope[name.Lexeme]; exists {
-		panic(
-			&ParseError{
-				token:   *name,
-				message: "Variable already declared in this scope.",
-			},
-		)
+		r.error(*name, "Already a variable with this name in this scope.")
 	}
 	scope[name.Lexeme] = false
-	r.inInitializer[name.Lexeme] = true
 }
 
 func (r *Resolver) define(name *Token) {
@@ -76,18 +89,21 @@ func (r *Resolver) define(name *Token) {
 	}
 	scope := r.scopes[len(r.scopes)-1]
 	scope[name.Lexeme] = true
-	delete(r.inInitializer, name.Lexeme)
 }
 
 func (r *Resolver) resolveLocal(expr Expr, name Token) {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, ok := r.scopes[i][name.Lexeme]; ok {
-			r.interpreter.resolve(expr, len(r.scopes)-1-i)
-			fmt.Fprintf(os.Stderr, "Resolved %s at distance %d\n", name.Lexeme, len(r.scopes)-1-i)
+			r.locals[expr] = len(r.scopes) - 1 - i
+			if r.trace != nil {
+				fmt.Fprintf(r.trace, "Resolved %s at distance %d\n", name.Lexeme, len(r.scopes)-1-i)
+			}
 			return
 		}
 	}
-	fmt.Fprintf(os.Stderr, "Did NOT resolve %s\n", name.Lexeme)
+	if r.trace != nil {
+		fmt.Fprintf(r.trace, "Did NOT resolve %s\n", name.Lexeme)
+	}
 }
 
 func (r *Resolver) VisitBinaryExpr(expr *Binary) interface{} {
@@ -128,9 +144,14 @@ func (r *Resolver) VisitVarStmt(stmt *Var) interface{} {
 }
 
 func (r *Resolver) VisitVariableExpr(expr *Variable) interface{} {
-	if len(r.scopes) > 0 {
-		if val, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !val {
-			panic("Can't read local variable in its own initializer.")
+	// A scope maps a name to false from declare until define, while its
+	// initializer is resolved. Only the scope the name resolves to counts.
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if defined, ok := r.scopes[i][expr.Name.Lexeme]; ok {
+			if !defined {
+				r.error(expr.Name, "Can't read local variable in its own initializer.")
+			}
+			break
 		}
 	}
 	r.resolveLocal(expr, expr.Name)
@@ -174,7 +195,7 @@ func (r *Resolver) resolveFunction(function *Function, funcType FunctionType) {
 	}
 
 	if function.Body != nil {
-		r.Resolve(function.Body)
+		r.resolveStatements(function.Body)
 	}
 
 	r.endScope()
@@ -184,7 +205,7 @@ func (r *Resolver) resolveFunction(function *Function, funcType FunctionType) {
 
 func (r *Resolver) VisitBlockStmt(stmt *Block) interface{} {
 	r.beginScope()
-	r.Resolve(stmt.Statements)
+	r.resolveStatements(stmt.Statements)
 	r.endScope()
 	return nil
 }
@@ -205,10 +226,10 @@ func (r *Resolver) VisitPrintStmt(stmt *Print) interface{} {
 
 func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) interface{} {
 	if r.currentFunction == NONE {
-		panic(fmt.Errorf("Can't return from top-level code"))
+		r.error(stmt.Keyword, "Can't return from top-level code.")
 	}
 	if r.currentFunction == INITIALIZER && stmt.Value != nil {
-		panic(fmt.Errorf("[line %d] Error at 'return': Can't return a value from an initializer.", stmt.Keyword.Line))
+		r.error(stmt.Keyword, "Can't return a value from an initializer.")
 	}
 	if stmt.Value != nil {
 		r.resolveExpr(stmt.Value)
@@ -222,7 +243,10 @@ func (r *Resolver) VisitWhileStmt(stmt *While) interface{} {
 	return nil
 }
 
-func (r *Resolver) Resolve(statements interface{}) {
+// Resolve resolves a program, a statement or an expression. The error
+// returned joins a *ResolveError for every static error found.
+func (r *Resolver) Resolve(statements interface{}) error {
+	r.errors = nil
 	switch v := statements.(type) {
 	case []Stmt:
 		for _, statement := range v {
@@ -233,8 +257,9 @@ func (r *Resolver) Resolve(statements interface{}) {
 	case Expr:
 		r.resolveExpr(v)
 	default:
-		fmt.Println("Unknown type in resolver:", reflect.TypeOf(statements))
+		return fmt.Errorf("unknown type in resolver: %v", reflect.TypeOf(statements))
 	}
+	return errors.Join(r.errors...)
 }
 
 func (r *Resolver) resolveStmt(stmt Stmt) {
@@ -288,7 +313,7 @@ func (r *Resolver) VisitClassStmt(stmt *Class) interface{} {
 		r.currentClass = IN_SUBCLASS
 		if superVar, ok := stmt.Superclass.(*Variable); ok {
 			if stmt.Name.Lexeme == superVar.Name.Lexeme {
-				panic(fmt.Errorf("A class can't inherit from itself."))
+				r.error(superVar.Name, "A class can't inherit from itself.")
 			}
 		}
 		r.resolveExpr(stmt.Superclass)
@@ -330,7 +355,7 @@ func (r *Resolver) VisitSetExpr(expr *Set) interface{} {
 
 func (r *Resolver) VisitThisExpr(expr *This) interface{} {
 	if r.currentClass == NO_CLASS {
-		panic("Cannot use 'this' outside of a class method.")
+		r.error(expr.Keyword, "Can't use 'this' outside of a class.")
 	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil
@@ -338,9 +363,9 @@ func (r *Resolver) VisitThisExpr(expr *This) interface{} {
 
 func (r *Resolver) VisitSuperExpr(expr *Super) interface{} {
 	if r.currentClass == NO_CLASS {
-		panic("Can't use 'super' outside of a class.")
+		r.error(expr.Keyword, "Can't use 'super' outside of a class.")
 	} else if r.currentClass != IN_SUBCLASS {
-		panic("Can't use 'super' in a class with no superclass.")
+		r.error(expr.Keyword, "Can't use 'super' in a class with no superclass.")
 	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil
@@ -0,0 +1,86 @@
+package lox
+
+import (
+	"reflect"
+	"testing"
+)
+
+// resolveErrors parses and resolves source and returns every resolve
+// error found.
+func resolveErrors(t *testing.T, source string) []string {
+	t.Helper()
+	statements, err := parseProgram(source)
+	if err != nil {
+		t.Fatalf("%q: %v", source, err)
+	}
+	err = NewResolver().Resolve(statements)
+	if err == nil {
+		return nil
+	}
+	var messages []string
+	for _, err := range unjoin(err) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestResolverErrors(t *testing.T) {
+	tests := map[string]string{
+		`{ var a = 1; var a = 2; }`:             "[line 1] Error at 'a': Already a variable with this name in this scope.",
+		`fun f(a, a) {}`:                        "[line 1] Error at 'a': Already a variable with this name in this scope.",
+		`var a = 1; { var a = a; }`:             "[line 1] Error at 'a': Can't read local variable in its own initializer.",
+		`return 1;`:                             "[line 1] Error at 'return': Can't return from top-level code.",
+		`class C { init() { return 1; } }`:      "[line 1] Error at 'return': Can't return a value from an initializer.",
+		`print this;`:                           "[line 1] Error at 'this': Can't use 'this' outside of a class.",
+		`fun f() { return this; }`:              "[line 1] Error at 'this': Can't use 'this' outside of a class.",
+		`print super.method();`:                 "[line 1] Error at 'super': Can't use 'super' outside of a class.",
+		`class C { m() { return super.m(); } }`: "[line 1] Error at 'super': Can't use 'super' in a class with no superclass.",
+		`class C < C {}`:                        "[line 1] Error at 'C': A class can't inherit from itself.",
+	}
+	for source, want := range tests {
+		if got := resolveErrors(t, source); !reflect.DeepEqual(got, []string{want}) {
+			t.Errorf("%s:\n got %q\nwant %q", source, got, want)
+		}
+	}
+}
+
+func TestResolverAllowsValidPrograms(t *testing.T) {
+	for _, source := range []string{
+		`var a = 1; var a = 2;`,
+		`var a = a;`,
+		`{ var a = 1; { var a = 2; } }`,
+		`class C { init() { return; } }`,
+		`{ var a = 1; { var b = a; } }`,
+		`fun f(a) { var b = a; { var a = b; } }`,
+		`class A { m() {} } class B < A { m() { return super.m(); } }`,
+	} {
+		if got := resolveErrors(t, source); got != nil {
+			t.Errorf("%s: %q", source, got)
+		}
+	}
+}
+
+func TestResolverReportsEveryError(t *testing.T) {
+	source := `return 1;
+{
+  var a = 1;
+  var a = a;
+}
+class C < C {
+  init() {
+    return this;
+  }
+}
+print super.x;`
+	want := []string{
+		"[line 1] Error at 'return': Can't return from top-level code.",
+		"[line 4] Error at 'a': Already a variable with this name in this scope.",
+		"[line 4] Error at 'a': Can't read local variable in its own initializer.",
+		"[line 6] Error at 'C': A class can't inherit from itself.",
+		"[line 8] Error at 'return': Can't return a value from an initializer.",
+		"[line 11] Error at 'super': Can't use 'super' outside of a class.",
+	}
+	if got := resolveErrors(t, source); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
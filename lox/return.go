@@ -1,10 +1,11 @@
-package main
+package lox
 
 type ReturnValue struct {
 	Value interface{}
 }
 
 type ReturnStmt struct {
+	Spanned
 	Keyword Token
 	Value   Expr
 }
@@ -1,17 +1,24 @@
-package main
+package lox
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 )
 
 type Scanner struct {
-	source  string
-	tokens  []Token
-	start   int
-	current int
-	line    int
-	errors  []error
+	source    string
+	tokens    []Token
+	start     int
+	current   int
+	line      int
+	lineStart int
+	// startLine and column locate the start of the current token, which a
+	// multi-line string leaves on an earlier line than line.
+	startLine int
+	column    int
+	trivia    []Trivia
+	errors    []error
 }
 
 func NewScanner(source string) *Scanner {
@@ -28,25 +35,29 @@ func NewScanner(source string) *Scanner {
 func (s *Scanner) ScanTokens() ([]Token, []error) {
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.column = s.start - s.lineStart + 1
 		if err := s.scanToken(); err != nil {
 			s.errors = append(s.errors, err)
 		}
 	}
 
-	s.tokens = append(s.tokens, NewToken(EOF, "", nil, s.line))
+	s.start = s.current
+	s.startLine = s.line
+	s.column = s.start - s.lineStart + 1
+	s.addToken(EOF, nil)
 	return s.tokens, s.errors
 }
 
 func (s *Scanner) string() error {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.advance() == '\n' {
+			s.newline()
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return fmt.Errorf("[line %d] Error: Unterminated string.", s.line)
+		return s.error("Unterminated string.")
 	}
 
 	s.advance()
@@ -108,13 +119,18 @@ func (s *Scanner) scanToken() error {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.addTrivia(CommentTrivia)
 		} else {
 			s.addToken(SLASH, nil)
 		}
 	case ' ', '\r', '\t':
-
+		for s.peek() == ' ' || s.peek() == '\r' || s.peek() == '\t' {
+			s.advance()
+		}
+		s.addTrivia(WhitespaceTrivia)
 	case '\n':
-		s.line++
+		s.newline()
+		s.addTrivia(NewlineTrivia)
 	case '"':
 		if err := s.string(); err != nil {
 			return err
@@ -125,7 +141,7 @@ func (s *Scanner) scanToken() error {
 		} else if isAlpha(c) {
 			s.identifier()
 		} else {
-			s.errors = append(s.errors, fmt.Errorf("[line %d] Error: Unexpected character: %c", s.line, c))
+			s.errors = append(s.errors, s.error(fmt.Sprintf("Unexpected character: %c", c)))
 		}
 	}
 	return nil
@@ -160,7 +176,7 @@ func (s *Scanner) number() {
 	number := s.source[s.start:s.current]
 	value, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		s.errors = append(s.errors, fmt.Errorf("[line %d] Error: Invalid number: %s", s.line, number))
+		s.errors = append(s.errors, s.error(fmt.Sprintf("Invalid number: %s", number)))
 		return
 	}
 	s.addToken(NUMBER, value)
@@ -200,9 +216,36 @@ func (s *Scanner) advance() byte {
 	return s.source[s.current-1]
 }
 
+// newline records that the character just consumed ended a line.
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
 func (s *Scanner) addToken(tokenType TokenType, literal interface{}) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, NewToken(tokenType, text, literal, s.line))
+	token := NewToken(tokenType, text, literal, s.startLine)
+	token.Column = s.column
+	token.Start = s.start
+	token.End = s.current
+	token.Leading = s.trivia
+	s.trivia = nil
+	s.tokens = append(s.tokens, token)
+}
+
+// addTrivia keeps the whitespace or comment just scanned so it can be
+// attached to the next token.
+func (s *Scanner) addTrivia(kind TriviaKind) {
+	s.trivia = append(s.trivia, Trivia{Kind: kind, Text: s.source[s.start:s.current]})
+}
+
+// error reports a problem with the text scanned since the token started.
+func (s *Scanner) error(message string) *ScanError {
+	err := NewScanError(s.line, message)
+	err.column = s.column
+	err.start = s.start
+	err.end = s.current
+	return err
 }
 
 func isDigit(c byte) bool {
@@ -237,3 +280,13 @@ var keywords = map[string]TokenType{
 	"var":    VAR,
 	"while":  WHILE,
 }
+
+// Keywords returns the reserved words in sorted order.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,25 @@
+package lox
+
+import "testing"
+
+func TestScannerMultiLineStringPosition(t *testing.T) {
+	tokens, errs := NewScanner("var s =  \"one\ntwo\";\nprint s;").ScanTokens()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, test := range []struct {
+		index        int
+		lexeme       string
+		line, column int
+	}{
+		{3, "\"one\ntwo\"", 1, 10},
+		{4, ";", 2, 5},
+		{5, "print", 3, 1},
+	} {
+		token := tokens[test.index]
+		if token.Lexeme != test.lexeme || token.Line != test.line || token.Column != test.column {
+			t.Errorf("token %d = %q at %d:%d, want %q at %d:%d",
+				test.index, token.Lexeme, token.Line, token.Column, test.lexeme, test.line, test.column)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lox
+
+import "context"
+
+// Session runs a sequence of source chunks against shared globals, like the
+// cells of a notebook. Each chunk is resolved on its own, so variables,
+// functions and classes declared by earlier chunks stay visible to later
+// ones while resolver state never leaks between chunks.
+type Session struct {
+	opts        Options
+	interpreter *Interpreter
+}
+
+// Result is the outcome of running a chunk in a Session.
+type Result struct {
+	// Value is the value of the chunk's final statement when IsExpression
+	// is true.
+	Value Value
+	// IsExpression reports whether the chunk ended with an expression
+	// statement.
+	IsExpression bool
+}
+
+func NewSession(opts Options) *Session {
+	return &Session{
+		opts:        opts,
+		interpreter: NewInterpreter(opts),
+	}
+}
+
+// Run executes one chunk of source.
+func (s *Session) Run(source string) (Result, error) {
+	return s.RunContext(s.interpreter.ctx, source)
+}
+
+// RunContext is like Run but stops executing once ctx is done.
+func (s *Session) RunContext(ctx context.Context, source string) (Result, error) {
+	return s.RunFileContext(ctx, "", source)
+}
+
+// RunFile is like Run for a chunk read from the named file, which runtime
+// errors raised in its functions are reported against.
+func (s *Session) RunFile(name, source string) (Result, error) {
+	return s.RunFileContext(s.interpreter.ctx, name, source)
+}
+
+// RunFileContext is like RunFile but stops executing once ctx is done.
+func (s *Session) RunFileContext(ctx context.Context, name, source string) (Result, error) {
+	last, isExpression, err := s.interpreter.run(ctx, &chunkSource{name: name, text: source})
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Value: NewValue(last), IsExpression: isExpression}, nil
+}
+
+// Reset discards every global defined by previous chunks, along with any
+// natives or host values registered on the old interpreter, and closes it.
+func (s *Session) Reset() {
+	s.interpreter.Close()
+	s.interpreter = NewInterpreter(s.opts)
+}
+
+// Close closes the session's interpreter.
+func (s *Session) Close() error {
+	return s.interpreter.Close()
+}
+
+// Interpreter returns the interpreter the session currently runs chunks on.
+// It changes after Reset.
+func (s *Session) Interpreter() *Interpreter {
+	return s.interpreter
+}
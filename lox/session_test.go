@@ -0,0 +1,79 @@
+package lox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSessionCarriesDefinitionsBetweenChunks(t *testing.T) {
+	var output bytes.Buffer
+	session := NewSession(Options{Stdout: &output})
+	chunks := []string{
+		`var greeting = "hi";`,
+		`fun counter() { var n = 0; fun next() { n = n + 1; return n; } return next; }`,
+		`var next = counter(); next();`,
+		`class Greeter { init(name) { this.name = name; } greet() { return greeting + " " + this.name; } }`,
+		`class Loud < Greeter { greet() { return super.greet() + "!"; } }`,
+		`print Loud("ada").greet();`,
+	}
+	for _, chunk := range chunks {
+		if _, err := session.Run(chunk); err != nil {
+			t.Fatalf("%s: %v", chunk, err)
+		}
+	}
+
+	result, err := session.Run(`next();`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsExpression || result.Value.String() != "2" {
+		t.Errorf("next() = %v (expression %v), want 2", result.Value, result.IsExpression)
+	}
+	if want := "hi ada!\n"; output.String() != want {
+		t.Errorf("output = %q, want %q", output.String(), want)
+	}
+}
+
+func TestSessionChunkResult(t *testing.T) {
+	session := NewSession(Options{Stdout: &bytes.Buffer{}})
+	result, err := session.Run(`var x = 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsExpression {
+		t.Errorf("a declaration reported a value: %v", result.Value)
+	}
+
+	// A chunk that fails to resolve leaves the session usable.
+	if _, err := session.Run(`return 1;`); err == nil {
+		t.Error("top-level return ran, want a resolve error")
+	}
+	result, err = session.Run(`x + 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value.String() != "2" {
+		t.Errorf("x + 1 = %v, want 2", result.Value)
+	}
+}
+
+func TestSessionReset(t *testing.T) {
+	session := NewSession(Options{Stdout: &bytes.Buffer{}})
+	if _, err := session.Run(`var x = 1; fun f() { return x; } class C {}`); err != nil {
+		t.Fatal(err)
+	}
+	session.Reset()
+
+	for _, name := range []string{"x", "f", "C"} {
+		if _, ok := session.Interpreter().Global(name); ok {
+			t.Errorf("%s survived Reset", name)
+		}
+	}
+	_, err := session.Run(`print x;`)
+	if got, want := runtimeMessage(t, err), "Undefined variable 'x'."; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if _, err := session.Run(`var x = "again"; print x;`); err != nil {
+		t.Fatal(err)
+	}
+}
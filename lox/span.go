@@ -0,0 +1,40 @@
+package lox
+
+// Span is the byte range of source a node was parsed from, from the start
+// of its first token to the end of its last. End is exclusive.
+type Span struct {
+	Start int
+	End   int
+}
+
+// IsZero reports whether the span is unset, as for nodes the parser
+// synthesizes or builds without source.
+func (s Span) IsZero() bool {
+	return s == Span{}
+}
+
+// Text returns the part of source the span covers.
+func (s Span) Text(source string) string {
+	if s.Start < 0 || s.End > len(source) || s.Start > s.End {
+		return ""
+	}
+	return source[s.Start:s.End]
+}
+
+// Spanned is embedded in every syntax tree node to give it a Span.
+type Spanned struct {
+	span Span
+}
+
+func (s *Spanned) Span() Span {
+	return s.span
+}
+
+// SetSpan sets the node's source range, for trees built outside the parser.
+func (s *Spanned) SetSpan(span Span) {
+	s.span = span
+}
+
+func spanBetween(first, last Token) Spanned {
+	return Spanned{span: Span{Start: first.Start, End: last.End}}
+}
@@ -0,0 +1,142 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FrameKind says what a Frame is running.
+type FrameKind int
+
+const (
+	FunctionFrame FrameKind = iota
+	InitializerFrame
+	NativeFrame
+)
+
+// Frame is one call in progress on the interpreter's call stack.
+type Frame struct {
+	// Name is the callee as tracebacks show it, such as "area()" or
+	// "Point.init()".
+	Name string
+	Kind FrameKind
+	// Line is the line of the call site, or 0 for a call made through the
+	// embedding API.
+	Line int
+	// Source names the file holding the call site, if it was run with
+	// RunFile.
+	Source string
+}
+
+func newFrame(callee LoxCallable, line int) Frame {
+	switch callee := callee.(type) {
+	case *LoxFunction:
+		name := callee.declaration.Name.Lexeme
+		if name == "" {
+			name = "<anonymous>"
+		}
+		return Frame{Name: name + "()", Kind: FunctionFrame, Line: line}
+	case *LoxClass:
+		if callee.FindMethod("init") == nil {
+			return Frame{Name: callee.name + "()", Kind: InitializerFrame, Line: line}
+		}
+		return Frame{Name: callee.name + ".init()", Kind: InitializerFrame, Line: line}
+	case *NativeFunction:
+		return Frame{Name: callee.name + "()", Kind: NativeFrame, Line: line}
+	}
+	return Frame{Name: fmt.Sprint(callee), Kind: NativeFrame, Line: line}
+}
+
+// Stack returns the calls in progress, innermost first. A native can use it
+// to see where it was called from.
+func (i *Interpreter) Stack() []Frame {
+	return reverseFrames(i.frames)
+}
+
+func reverseFrames(frames []Frame) []Frame {
+	stack := make([]Frame, len(frames))
+	for index, frame := range frames {
+		stack[len(frames)-1-index] = frame
+	}
+	return stack
+}
+
+// traceback describes stack, innermost first, for an error raised at line
+// of the named source: each entry names a function and the line it had
+// reached, ending with the top-level code as "main". Lines in a file other
+// than the error's name their file.
+func traceback(stack []Frame, line int, source string) []string {
+	if len(stack) == 0 {
+		return nil
+	}
+	at := func(name string, line int, file string) string {
+		entry := fmt.Sprintf("at %s line %d", name, line)
+		if file != "" && file != source {
+			entry += " in " + file
+		}
+		return entry
+	}
+
+	var entries []string
+	file := source
+	for _, frame := range stack {
+		switch {
+		case frame.Kind == NativeFrame:
+			entries = append(entries, fmt.Sprintf("at %s (native)", frame.Name))
+		case line > 0:
+			entries = append(entries, at(frame.Name, line, file))
+		default:
+			entries = append(entries, "at "+frame.Name)
+		}
+		line, file = frame.Line, frame.Source
+	}
+	// The outermost call came from the host rather than from a script.
+	if line > 0 {
+		entries = append(entries, at("main", line, file))
+	}
+	return entries
+}
+
+// maxTraceback is how many traceback entries a diagnostic shows; deep
+// recursion keeps the innermost and outermost calls.
+const maxTraceback = 20
+
+// shortTraceback collapses runs of identical entries, as left by simple
+// recursion, and keeps at most maxTraceback of the rest.
+func shortTraceback(entries []string) []string {
+	var collapsed []string
+	for start := 0; start < len(entries); {
+		end := start + 1
+		for end < len(entries) && entries[end] == entries[start] {
+			end++
+		}
+		if end-start > 1 {
+			collapsed = append(collapsed, fmt.Sprintf("%s (repeated %d times)", entries[start], end-start))
+		} else {
+			collapsed = append(collapsed, entries[start])
+		}
+		start = end
+	}
+
+	entries = collapsed
+	if len(entries) <= maxTraceback {
+		return entries
+	}
+	half := maxTraceback / 2
+	short := append([]string{}, entries[:half]...)
+	short = append(short, fmt.Sprintf("... %d more calls", len(entries)-maxTraceback))
+	return append(short, entries[len(entries)-half:]...)
+}
+
+// Traceback returns where the error was raised and the calls that led
+// there, innermost first, as in "at area() line 12, at main line 30". It is
+// empty for errors raised in top-level code.
+func (e *RuntimeError) Traceback() string {
+	return strings.Join(traceback(e.stack, e.token.Line, e.source.sourceName()), ", ")
+}
+
+// Stack returns the calls in progress when the error was raised, innermost
+// first.
+func (e *RuntimeError) Stack() []Frame {
+	return e.stack
+}
@@ -0,0 +1,92 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// runtimeError returns the runtime error err holds.
+func runtimeError(t *testing.T, err error) *RuntimeError {
+	t.Helper()
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a runtime error, got %v", err)
+	}
+	return runtimeErr
+}
+
+func TestTracebackFrameOrder(t *testing.T) {
+	source := `fun a() { b(); }
+fun b() { c(); }
+fun c() { nil + 1; }
+a();`
+	err := runtimeError(t, New(Options{Stdout: &bytes.Buffer{}}).Run(source))
+
+	if got, want := err.Traceback(), "at c() line 3, at b() line 2, at a() line 1, at main line 4"; got != want {
+		t.Errorf("Traceback() = %q, want %q", got, want)
+	}
+	var names []string
+	for _, frame := range err.Stack() {
+		names = append(names, frame.Name)
+	}
+	if want := []string{"c()", "b()", "a()"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Stack() = %v, want %v", names, want)
+	}
+}
+
+func TestTracebackAcrossFiles(t *testing.T) {
+	interpreter := New(Options{Stdout: &bytes.Buffer{}})
+	if err := interpreter.RunFile("lib.lox", "fun boom() {\n  return nil + 1;\n}"); err != nil {
+		t.Fatal(err)
+	}
+	err := runtimeError(t, interpreter.RunFile("main.lox", "var x = 1;\n\nboom();"))
+
+	want := []string{"at boom() line 2", "at main line 3 in main.lox"}
+	diagnostic := err.Diagnostic()
+	if !reflect.DeepEqual(diagnostic.Notes, want) {
+		t.Errorf("notes = %q, want %q", diagnostic.Notes, want)
+	}
+	// The error itself is reported against the file boom was loaded from.
+	if diagnostic.Filename != "lib.lox" {
+		t.Errorf("filename = %q, want lib.lox", diagnostic.Filename)
+	}
+}
+
+func TestTracebackCollapsesStackOverflow(t *testing.T) {
+	interpreter := New(Options{Limits: Limits{MaxCallDepth: 100}})
+	err := runtimeError(t, interpreter.Run("fun f() {\n  f();\n}\nf();"))
+
+	if got, want := err.Message(), "Stack overflow."; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+	want := []string{"at f() line 2 (repeated 100 times)", "at main line 4"}
+	if got := err.Diagnostic().Notes; !reflect.DeepEqual(got, want) {
+		t.Errorf("notes = %q, want %q", got, want)
+	}
+}
+
+func TestTracebackKeepsInnermostAndOutermostCalls(t *testing.T) {
+	source := `fun even(n) {
+  if (n == 0) return nil + 1;
+  return odd(n - 1);
+}
+fun odd(n) { return even(n - 1); }
+even(60);`
+	err := runtimeError(t, New(Options{}).Run(source))
+
+	notes := err.Diagnostic().Notes
+	if len(notes) != maxTraceback+1 {
+		t.Fatalf("got %d notes, want %d: %q", len(notes), maxTraceback+1, notes)
+	}
+	if got, want := notes[0], "at even() line 2"; got != want {
+		t.Errorf("innermost = %q, want %q", got, want)
+	}
+	if got, want := notes[maxTraceback/2], "... 42 more calls"; got != want {
+		t.Errorf("gap = %q, want %q", got, want)
+	}
+	if got, want := notes[len(notes)-1], "at main line 6"; got != want {
+		t.Errorf("outermost = %q, want %q", got, want)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package lox
 
 type StmtVisitor interface {
 	VisitPrintStmt(stmt *Print) interface{}
@@ -15,37 +15,49 @@ type StmtVisitor interface {
 
 type Stmt interface {
 	Accept(visitor StmtVisitor) interface{}
+	Span() Span
 }
 
 type Print struct {
+	Spanned
+	Keyword    Token
 	Expression Expr
 }
 
 type Expression struct {
+	Spanned
 	Expression Expr
 }
 
 type Var struct {
+	Spanned
 	Name        Token
 	Initializer Expr
 }
 
 type Block struct {
+	Spanned
+	Brace      Token
 	Statements []Stmt
 }
 
 type If struct {
+	Spanned
+	Keyword    Token
 	Condition  Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
 }
 
 type While struct {
+	Spanned
+	Keyword   Token
 	Condition Expr
 	Body      Stmt
 }
 
 type Class struct {
+	Spanned
 	Name       Token
 	Methods    []Stmt
 	Superclass Expr
@@ -87,6 +99,11 @@ func (r *Resolver) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitResolverStmt(r)
 }
 
+// Span is always zero; a Resolver never comes from source.
+func (r *Resolver) Span() Span {
+	return Span{}
+}
+
 func (c *Class) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitClassStmt(c)
 }
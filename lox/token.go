@@ -1,4 +1,4 @@
-package main
+package lox
 
 import "fmt"
 
@@ -9,6 +9,30 @@ type Token struct {
 	Lexeme  string
 	Literal interface{}
 	Line    int
+	// Column is the 1-based byte column where the token starts.
+	Column int
+	// Start and End are the byte offsets of the lexeme in the source; End
+	// is exclusive.
+	Start int
+	End   int
+	// Leading holds the whitespace and comments between the previous token
+	// and this one. The EOF token carries whatever trails the last token.
+	Leading []Trivia
+}
+
+type TriviaKind int
+
+const (
+	WhitespaceTrivia TriviaKind = iota
+	NewlineTrivia
+	CommentTrivia
+)
+
+// Trivia is source text the parser ignores but tools such as the formatter
+// need to reproduce.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
 }
 
 const (
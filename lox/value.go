@@ -0,0 +1,127 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Kind identifies the dynamic type of a Lox value.
+type Kind int
+
+const (
+	NilKind Kind = iota
+	BoolKind
+	NumberKind
+	StringKind
+	FunctionKind
+	ClassKind
+	InstanceKind
+	ListKind
+	MapKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return "bool"
+	case NumberKind:
+		return "number"
+	case StringKind:
+		return "string"
+	case FunctionKind:
+		return "function"
+	case ClassKind:
+		return "class"
+	case InstanceKind:
+		return "instance"
+	case ListKind:
+		return "list"
+	case MapKind:
+		return "map"
+	}
+	return "unknown"
+}
+
+// Value is a Lox value handed back to Go code.
+type Value struct {
+	raw interface{}
+}
+
+func NewValue(raw interface{}) Value {
+	return Value{raw: raw}
+}
+
+func (v Value) Kind() Kind {
+	switch v.raw.(type) {
+	case nil:
+		return NilKind
+	case bool:
+		return BoolKind
+	case float64:
+		return NumberKind
+	case string:
+		return StringKind
+	case *LoxClass:
+		return ClassKind
+	case *LoxInstance, *HostInstance:
+		return InstanceKind
+	case *LoxList:
+		return ListKind
+	case *LoxMap:
+		return MapKind
+	case LoxCallable:
+		return FunctionKind
+	}
+	return NilKind
+}
+
+// Interface returns the underlying Lox value.
+func (v Value) Interface() interface{} {
+	return v.raw
+}
+
+func (v Value) IsNil() bool {
+	return v.raw == nil
+}
+
+func (v Value) Bool() (bool, bool) {
+	b, ok := v.raw.(bool)
+	return b, ok
+}
+
+func (v Value) Number() (float64, bool) {
+	n, ok := v.raw.(float64)
+	return n, ok
+}
+
+func (v Value) Str() (string, bool) {
+	s, ok := v.raw.(string)
+	return s, ok
+}
+
+// Equal reports whether two values are equal under Lox's == operator.
+func (v Value) Equal(other Value) bool {
+	return isEqual(v.raw, other.raw)
+}
+
+// String formats the value the way the print statement does.
+func (v Value) String() string {
+	return Stringify(v.raw)
+}
+
+// Decode stores the value in the Go variable target points to, converting
+// it with FromLox.
+func (v Value) Decode(target interface{}) error {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", target)
+	}
+	converted, err := FromLox(v.raw, pointer.Type().Elem())
+	if err != nil {
+		return err
+	}
+	pointer.Elem().Set(converted)
+	return nil
+}